@@ -0,0 +1,101 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/yawn/instagpu/database"
+	"github.com/yawn/instagpu/detect"
+)
+
+var catalogDatabasePath string
+
+var catalogCmd = &cobra.Command{
+
+	Use:   "catalog",
+	Short: "List device performance data and instances missing it",
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		catalog := detect.CurrentCatalog()
+
+		for _, tag := range slices.Sorted(maps.Keys(catalog)) {
+			fmt.Printf("📇 %s\t%s\n", tag, catalogDevice(catalog[tag]))
+		}
+
+		db, err := database.Load(catalogDatabasePath)
+
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			return nil // nothing discovered yet
+		case err != nil:
+			return err
+		}
+
+		missing := make(map[string][]string)
+
+		for _, prices := range db.Prices {
+
+			tag := prices.Instance.GPU.Tag()
+
+			if _, ok := catalog[tag]; !ok && !slices.Contains(missing[tag], prices.Instance.Name) {
+				missing[tag] = append(missing[tag], prices.Instance.Name)
+			}
+
+		}
+
+		for _, tag := range slices.Sorted(maps.Keys(missing)) {
+			slices.Sort(missing[tag])
+			fmt.Printf("❓ %s\tmissing, used by %s\n", tag, strings.Join(missing[tag], ", "))
+		}
+
+		return nil
+
+	},
+}
+
+// catalogDevice formats all known metrics of a device
+func catalogDevice(device *detect.Device) string {
+
+	var b strings.Builder
+
+	for _, metric := range []struct {
+		name  string
+		unit  string
+		value *float64
+	}{
+		{"fp32", "TFLOPS", device.FP32},
+		{"fp16", "TFLOPS", device.FP16},
+		{"bf16", "TFLOPS", device.BF16},
+		{"fp8", "TFLOPS", device.FP8},
+		{"int8", "TOPS", device.INT8},
+		{"bandwidth", "GB/s", device.Bandwidth},
+	} {
+
+		if metric.value != nil {
+			fmt.Fprintf(&b, "⚡ %.2f %s %s\t", *metric.value, metric.unit, metric.name)
+		}
+
+	}
+
+	if device.Source != "" {
+		fmt.Fprintf(&b, "🔗 %s", device.Source)
+	}
+
+	return b.String()
+
+}
+
+func init() {
+
+	flags := catalogCmd.Flags()
+
+	flags.StringVar(&catalogDatabasePath, "database-path", "database.json", "Path to the pricing database, for listing instances missing data")
+
+	rootCmd.AddCommand(catalogCmd)
+
+}
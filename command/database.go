@@ -0,0 +1,189 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/yawn/instagpu/database"
+	"github.com/yawn/instagpu/database/score"
+	"github.com/yawn/instagpu/provider"
+)
+
+// cache controls how a database is reused across invocations
+type cache struct {
+	enabled bool
+	given   bool // takes the regions of a cached database as given, for commands not selecting any
+	maxAge  time.Duration
+	path    string
+	refresh bool
+}
+
+// open returns the cached database while it is fresh and fetches (and saves) a new one otherwise
+func open(ctx context.Context, c *cache, options *database.Options, providers ...provider.Provider) (*database.Database, error) {
+
+	logger := slog.Default().With(
+		slog.String("path", c.path),
+	)
+
+	var cached *database.Database
+
+	if c.enabled {
+
+		db, err := database.Load(c.path)
+
+		if err == nil {
+			cached = db // for carrying over imported vantage points when refetching
+		}
+
+		switch {
+		case err != nil:
+			logger.Debug("cache unusable", slog.String("error", err.Error()))
+		case c.refresh:
+			logger.Debug("cache refresh forced")
+		case db.IsMigrated():
+			logger.Debug("cache migrated from an older version")
+		case db.IsStale(c.maxAge):
+			logger.Debug("cache stale", slog.Time("fetched", db.Fetched))
+		case !c.given && !db.Covers(options.Regions):
+			logger.Debug("cache lacks selected regions", slog.Any("excluded", db.Excluded))
+		case options.Window != 0 && db.Window != options.Window:
+			logger.Debug("cache has other price window", slog.Duration("window", db.Window))
+		default:
+
+			logger.Debug("cache fresh", slog.Time("fetched", db.Fetched))
+
+			for _, prices := range db.Prices {
+				prices.Instance.GPU.MeasureTFLOPS() // the catalog may have changed since
+			}
+
+			return db, nil
+
+		}
+
+	}
+
+	options.Build = versionVersion
+
+	db, err := database.New(ctx, options, providers...)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to initialize database")
+	}
+
+	if cached != nil {
+		db.KeepVantagePoints(cached)
+	}
+
+	if c.enabled {
+
+		if err := db.Save(c.path); err != nil {
+			return nil, err
+		}
+
+		logger.Debug("cache saved")
+
+	}
+
+	return db, nil
+
+}
+
+// vantage imports the vantage point at from into the (cached) database, if given, and ranks by the latencies
+// of the named one, defaulting to the imported one and otherwise keeping the local ones
+func vantage(ctx context.Context, c *cache, db *database.Database, from, name string) error {
+
+	if from != "" {
+
+		vp, err := database.LoadVantagePoint(ctx, from)
+
+		if err != nil {
+			return err
+		}
+
+		db.AddVantagePoint(vp)
+
+		if c.enabled {
+
+			if err := db.Save(c.path); err != nil {
+				return err
+			}
+
+			slog.Debug("cache saved", slog.String("vantage", vp.Name))
+
+		}
+
+		if name == "" {
+			name = vp.Name
+		}
+
+	}
+
+	if name == "" {
+		return nil
+	}
+
+	return db.UseVantagePoint(name)
+
+}
+
+// summarize prints failures and warnings recorded while fetching a database
+func summarize(w io.Writer, db *database.Database) {
+
+	if len(db.Warnings) > 0 {
+
+		fmt.Fprintf(w, "ℹ️ %d instances skipped, lacking a supported model\n", len(db.Warnings))
+
+		for _, warning := range db.Warnings {
+			fmt.Fprintln(w, warning)
+		}
+
+	}
+
+	if len(db.Failures) == 0 {
+		return
+	}
+
+	regions := make(map[string]struct{})
+
+	for _, failure := range db.Failures {
+		regions[failure.Provider+"/"+failure.Region] = struct{}{}
+	}
+
+	fmt.Fprintf(w, "⚠️ %d failures in %d providers / regions, results may be incomplete (use --strict to abort instead)\n", len(db.Failures), len(regions))
+
+	for _, failure := range db.Failures {
+		fmt.Fprintln(w, failure)
+	}
+
+}
+
+// diagnose reports instances the scorer could not rank, grouped by the catalog entries they lack, hinting at
+// listing them unless already included
+func diagnose(w io.Writer, db *database.Database, scorer score.Scorer, includeUnscored bool) {
+
+	unscored := db.Unscored(scorer)
+
+	if len(unscored) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "❓ %d accelerators lack %s data in the catalog, their instances are unscored", len(unscored), scorer.Name())
+
+	if !includeUnscored {
+		fmt.Fprint(w, " (use --include-unscored to list them)")
+	}
+
+	fmt.Fprintln(w)
+
+	for _, tag := range slices.Sorted(maps.Keys(unscored)) {
+		fmt.Fprintf(w, "%s\t%s\n", tag, strings.Join(unscored[tag], ", "))
+	}
+
+}
@@ -0,0 +1,155 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yawn/instagpu/database"
+	"github.com/yawn/instagpu/database/score"
+	"github.com/yawn/instagpu/detect"
+	"github.com/yawn/instagpu/provider/fake"
+)
+
+func TestDiagnose(t *testing.T) {
+
+	assert := assert.New(t)
+
+	db := &database.Database{
+		Prices: []*detect.Prices{
+			{
+				Instance: &detect.Instance{
+					GPU:  &detect.GPU{Name: "Unknown", Vendor: "Vendor"},
+					Name: "u1.xlarge",
+				},
+			},
+		},
+	}
+
+	var hinted, included bytes.Buffer
+
+	diagnose(&hinted, db, score.Scorers["fp32"], false)
+	diagnose(&included, db, score.Scorers["fp32"], true)
+
+	assert.Contains(hinted.String(), "are unscored (use --include-unscored to list them)\n")
+	assert.Contains(included.String(), "are unscored\n")
+	assert.NotContains(included.String(), "--include-unscored")
+
+}
+
+func TestOpenKeepsVantagePoints(t *testing.T) {
+
+	assert := assert.New(t)
+
+	var (
+		ctx  = context.Background()
+		dir  = t.TempDir()
+		from = filepath.Join(dir, "ci.json")
+		c    = &cache{
+			enabled: true,
+			maxAge:  time.Hour,
+			path:    filepath.Join(dir, "database.json"),
+		}
+		options = &database.Options{
+			Prober: fake.Prober(10 * time.Millisecond),
+		}
+		p = fake.Sample()
+	)
+
+	require.NoError(t, os.WriteFile(from, []byte(`{"name":"ci","regions":{"fake-eu-west-1":{"avg":42,"samples":3}}}`), 0o644))
+
+	db, err := open(ctx, c, options, p)
+	require.NoError(t, err)
+	require.NoError(t, vantage(ctx, c, db, from, ""))
+
+	c.refresh = true
+
+	db, err = open(ctx, c, options, p)
+	require.NoError(t, err)
+
+	assert.Contains(db.Vantages, database.LOCAL, "measured anew")
+	assert.NoError(vantage(ctx, c, db, "", "ci"), "kept across refetches")
+
+	for _, prices := range db.Prices {
+
+		if prices.Instance.Region.Name == "eu-west-1" {
+			assert.Equal(42.0, prices.Instance.Region.Latency.Avg)
+		}
+
+	}
+
+	c.refresh = false
+
+	db, err = open(ctx, c, options, p)
+	require.NoError(t, err)
+
+	assert.Contains(db.Vantages, "ci", "saved with the refetched database")
+
+}
+
+func TestOpenRefetchesMigrated(t *testing.T) {
+
+	var (
+		ctx = context.Background()
+		c   = &cache{
+			enabled: true,
+			maxAge:  time.Hour,
+			path:    filepath.Join(t.TempDir(), "database.json"),
+		}
+		fetched = time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+	)
+
+	require.NoError(t, os.WriteFile(c.path, []byte(`{"version": 3, "fetched": "`+fetched+`", "window": 604800000000000, "prices": []}`), 0o644))
+
+	db, err := open(ctx, c, &database.Options{Prober: fake.Prober(time.Millisecond)}, fake.Sample())
+
+	require.NoError(t, err)
+	assert.NotEmpty(t, db.Prices, "fresh, but lacking data a migration cannot rebuild")
+	assert.EqualValues(t, database.VERSION, db.Version)
+
+}
+
+func TestOpenGivenRegions(t *testing.T) {
+
+	var (
+		ctx     = context.Background()
+		options = &database.Options{
+			Prober: fake.Prober(time.Millisecond),
+			Regions: func(name string) bool {
+				return name == "eu-west-1"
+			},
+		}
+		p = fake.Sample()
+	)
+
+	show := &cache{
+		enabled: true,
+		maxAge:  time.Hour,
+		path:    filepath.Join(t.TempDir(), "database.json"),
+	}
+
+	_, err := open(ctx, show, options, p)
+	require.NoError(t, err)
+
+	p.Fail(fake.Key("regions"), fmt.Errorf("must never be queried"))
+
+	launch := *show
+	launch.given = true
+
+	db, err := open(ctx, &launch, &database.Options{Prober: fake.Prober(time.Millisecond)}, p)
+
+	if assert.NoError(t, err, "cached database reused") {
+		assert.Equal(t, map[string][]string{fake.NAME: {"us-east-1"}}, db.Excluded)
+	}
+
+	_, err = open(ctx, show, &database.Options{Prober: fake.Prober(time.Millisecond)}, p)
+
+	assert.Error(t, err, "all regions selected, refetched")
+
+}
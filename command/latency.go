@@ -0,0 +1,75 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/yawn/instagpu/database"
+	"github.com/yawn/instagpu/detect"
+)
+
+var latencyOptions database.Options
+var latencyName string
+var latencyProbe string
+var latencyProviderAWS bool
+var latencyTimeout time.Duration
+
+var latencyCmd = &cobra.Command{
+
+	Use:   "latency",
+	Short: "Measure latencies to all regions from here, for use with show --latency-from elsewhere",
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		if latencyName == "" {
+			return fmt.Errorf("vantage point requires a name")
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), latencyTimeout)
+		defer cancel()
+
+		providers, err := configure(ctx, latencyProviderAWS)
+
+		if err != nil {
+			return err
+		}
+
+		latencyOptions.Prober, err = detect.LookupProber(latencyProbe)
+
+		if err != nil {
+			return err
+		}
+
+		vp, err := database.MeasureVantagePoint(ctx, latencyName, &latencyOptions, providers...)
+
+		if err != nil {
+			return err
+		}
+
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "\t")
+
+		return enc.Encode(vp)
+
+	},
+}
+
+func init() {
+
+	flags := latencyCmd.Flags()
+
+	hostname, _ := os.Hostname()
+
+	flags.BoolVar(&latencyOptions.Strict, "strict", false, "Abort if any region fails to measure")
+	flags.BoolVar(&latencyProviderAWS, "provider-aws", true, "Enable AWS")
+	flags.DurationVar(&latencyTimeout, "timeout", 30*time.Second, "Timeout for all API operations")
+	flags.IntVar(&latencyOptions.Samples, "latency-samples", detect.SAMPLES, "Latency samples per region")
+	flags.StringVar(&latencyProbe, "latency-probe", detect.PROBE, "Latency probe: icmp (needs privileges), tcp (connect to port 443), https (connect and TLS handshake) or auto, falling back in this order")
+	flags.StringVar(&latencyName, "name", hostname, "Name of the vantage point")
+
+	rootCmd.AddCommand(latencyCmd)
+
+}
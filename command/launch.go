@@ -0,0 +1,222 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/yawn/instagpu/database"
+	"github.com/yawn/instagpu/database/filter"
+	"github.com/yawn/instagpu/database/score"
+	"github.com/yawn/instagpu/detect"
+	"github.com/yawn/instagpu/provider"
+)
+
+// zone placements
+const (
+	zoneAny      = "any"
+	zoneCheapest = "cheapest"
+)
+
+var launchCache = cache{enabled: true, given: true} // ranks must match the ones show printed from the cache
+var launchCheapestZone bool
+var launchOptions provider.LaunchOptions
+var launchProviderAWS bool
+var launchScore string
+var launchSort string
+var launchTimeout time.Duration
+var launchVantage string
+var launchZone string
+var launchZoneInclude []string
+
+var launchCmd = &cobra.Command{
+
+	Use:   "launch (<rank> | <instance> <region>)",
+	Short: "Launch a spot instance for a ranked candidate",
+	Args:  cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		ctx, cancel := context.WithTimeout(context.Background(), launchTimeout)
+		defer cancel()
+
+		providers, err := configure(ctx, launchProviderAWS)
+
+		if err != nil {
+			return err
+		}
+
+		db, err := open(ctx, &launchCache, &database.Options{}, providers...)
+
+		if err != nil {
+			return err
+		}
+
+		if err := vantage(ctx, &launchCache, db, "", launchVantage); err != nil {
+			return err
+		}
+
+		var zones func(zone *detect.Zone) bool
+
+		if len(launchZoneInclude) > 0 {
+			zones = filter.MatchZones(launchZoneInclude)
+		}
+
+		prices, err := launchCandidate(db, args, zones)
+
+		if err != nil {
+			return err
+		}
+
+		if zones != nil && len(prices.Zones) == 0 {
+			return fmt.Errorf("instance %q not offered as spot in any zone matching %q", prices.Instance.Name, strings.Join(launchZoneInclude, ","))
+		}
+
+		launchOptions.Zone, err = launchPlacement(prices, launchZone)
+
+		if err != nil {
+			return err
+		}
+
+		provider, err := lookup(providers, prices.Instance.Region.Provider)
+
+		if err != nil {
+			return err
+		}
+
+		machine, err := provider.Launch(ctx, prices, &launchOptions)
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(machine)
+
+		return nil
+
+	},
+}
+
+// launchCandidate selects prices either by rank index or by instance and region name, limited to the selected
+// zones if any
+func launchCandidate(db *database.Database, args []string, zones func(zone *detect.Zone) bool) (*detect.Prices, error) {
+
+	if len(args) == 1 {
+
+		rank, err := strconv.Atoi(args[0])
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid rank %q", args[0])
+		}
+
+		scorer, err := score.Parse(launchScore)
+
+		if err != nil {
+			return nil, err
+		}
+
+		order, err := database.ParseOrder(launchSort)
+
+		if err != nil {
+			return nil, err
+		}
+
+		// unscored candidates rank after all scored ones, so ranks match show with or without --include-unscored
+		results := db.Filter(&database.Query{
+			Cheapest:        launchCheapestZone,
+			IncludeUnscored: true,
+			Max:             math.MaxUint16,
+			Order:           order,
+			Scorer:          scorer,
+			Zones:           zones,
+		})
+
+		for _, result := range results {
+
+			if result.Index == rank {
+				return result.Prices, nil
+			}
+
+		}
+
+		return nil, fmt.Errorf("no candidate with rank %d", rank)
+
+	}
+
+	for _, prices := range db.Prices {
+
+		if prices.Instance.Name != args[0] || prices.Instance.Region.Name != args[1] {
+			continue
+		}
+
+		if zones != nil {
+			prices = prices.InZones(zones)
+		}
+
+		return prices, nil
+
+	}
+
+	return nil, fmt.Errorf("no candidate for instance %q in region %q", args[0], args[1])
+
+}
+
+// launchPlacement resolves the availability zone to launch in, either by name, the cheapest one or any
+func launchPlacement(prices *detect.Prices, zone string) (string, error) {
+
+	switch zone {
+
+	case zoneAny:
+		return "", nil
+
+	case zoneCheapest:
+
+		cheapest := prices.Cheapest()
+
+		if cheapest == nil {
+			slog.Debug("zones unknown, leaving placement to the provider")
+			return "", nil
+		}
+
+		return cheapest.Name, nil
+
+	}
+
+	for _, known := range prices.Zones {
+
+		if known.Name == zone || known.ID == zone {
+			return known.Name, nil
+		}
+
+	}
+
+	return "", fmt.Errorf("instance %q not offered as spot in zone %q of region %q", prices.Instance.Name, zone, prices.Instance.Region.Name)
+
+}
+
+func init() {
+
+	flags := launchCmd.Flags()
+
+	flags.BoolVar(&launchCheapestZone, "cheapest-zone", false, "Rank by the prices of the cheapest availability zone, must match the one passed to show")
+	flags.BoolVar(&launchProviderAWS, "provider-aws", true, "Enable AWS")
+	flags.DurationVar(&launchCache.maxAge, "cache-max-age", 24*time.Hour, "Maximum age of a cached database before it is refetched")
+	flags.DurationVar(&launchTimeout, "timeout", 2*time.Minute, "Timeout for all API operations")
+	flags.Float64Var(&launchOptions.MaxPrice, "max-price", 0, "Maximum spot price in USD / h (defaults to the highest price observed)")
+	flags.StringVar(&launchCache.path, "database-path", "database.json", "Path to the pricing database, for caching")
+	flags.StringVar(&launchOptions.Image, "image", "", "Machine image to launch (defaults to the latest deep learning base image)")
+	flags.StringVar(&launchOptions.Key, "key", "", "Name of the SSH key pair to install (no default)")
+	flags.StringVar(&launchScore, "score", score.DEFAULT, "Scorer used for ranking, must match the one passed to show")
+	flags.StringVar(&launchSort, "sort", database.SORT, "Sort order used for ranking, must match the one passed to show")
+	flags.StringVar(&launchVantage, "vantage", "", "Vantage point of latencies used for ranking, must match the one passed to show (no default)")
+	flags.StringVar(&launchZone, "zone", zoneCheapest, "Availability zone to launch in by name or id, \""+zoneCheapest+"\" for the one with the lowest average price or \""+zoneAny+"\" to leave it to the provider")
+	flags.StringSliceVar(&launchZoneInclude, "filter-zone-include", nil, "Availability zones with ids or names matching comma-separated globs to choose the cheapest one from, must match the one passed to show (no default)")
+
+	rootCmd.AddCommand(launchCmd)
+
+}
@@ -0,0 +1,53 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var listProviderAWS bool
+var listTimeout time.Duration
+
+var listCmd = &cobra.Command{
+
+	Use:   "list",
+	Short: "List instances launched by instagpu",
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		ctx, cancel := context.WithTimeout(context.Background(), listTimeout)
+		defer cancel()
+
+		providers, err := configure(ctx, listProviderAWS)
+
+		if err != nil {
+			return err
+		}
+
+		machines, err := machines(ctx, providers)
+
+		if err != nil {
+			return err
+		}
+
+		for _, machine := range machines {
+			fmt.Println(machine)
+		}
+
+		return nil
+
+	},
+}
+
+func init() {
+
+	flags := listCmd.Flags()
+
+	flags.BoolVar(&listProviderAWS, "provider-aws", true, "Enable AWS")
+	flags.DurationVar(&listTimeout, "timeout", 30*time.Second, "Timeout for all API operations")
+
+	rootCmd.AddCommand(listCmd)
+
+}
@@ -0,0 +1,107 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/yawn/instagpu/detect"
+	"github.com/yawn/instagpu/provider"
+	"github.com/yawn/instagpu/provider/aws"
+	"golang.org/x/sync/errgroup"
+)
+
+// configure sets up all selected providers
+func configure(ctx context.Context, enableAWS bool) ([]provider.Provider, error) {
+
+	var providers []provider.Provider
+
+	if enableAWS {
+
+		provider, err := aws.New(ctx)
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to configure aws")
+		}
+
+		providers = append(providers, provider)
+
+	}
+
+	if len(providers) == 0 {
+		return nil, fmt.Errorf("no providers selected")
+	}
+
+	return providers, nil
+
+}
+
+// lookup finds a provider by name
+func lookup(providers []provider.Provider, name string) (provider.Provider, error) {
+
+	for _, provider := range providers {
+
+		if provider.Name() == name {
+			return provider, nil
+		}
+
+	}
+
+	return nil, fmt.Errorf("provider %q not selected", name)
+
+}
+
+// machines enumerates all machines launched by instagpu across all regions of the selected providers
+func machines(ctx context.Context, providers []provider.Provider) ([]*detect.Machine, error) {
+
+	wg, ctx := errgroup.WithContext(ctx)
+
+	var (
+		mutex   sync.Mutex
+		results []*detect.Machine
+	)
+
+	for _, provider := range providers {
+
+		regions, err := provider.Regions(ctx)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, region := range regions {
+
+			wg.Go(func() error {
+
+				machines, err := provider.Machines(ctx, region)
+
+				if err != nil {
+					return err
+				}
+
+				mutex.Lock()
+				defer mutex.Unlock()
+
+				results = append(results, machines...)
+
+				return nil
+
+			})
+
+		}
+
+	}
+
+	if err := wg.Wait(); err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(results, func(a, b *detect.Machine) int {
+		return a.Launched.Compare(b.Launched)
+	})
+
+	return results, nil
+
+}
@@ -1,19 +1,23 @@
 package command
 
 import (
+	"errors"
+	"io/fs"
 	"log/slog"
 	"os"
 
 	"github.com/spf13/cobra"
+	"github.com/yawn/instagpu/detect"
 )
 
 const app = "instagpu"
 
+var rootCatalog string
 var rootDebug bool
 
 var rootCmd = &cobra.Command{
 	Use: app,
-	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 
 		logOptions := &slog.HandlerOptions{}
 
@@ -25,14 +29,52 @@ var rootCmd = &cobra.Command{
 			slog.String("version", versionVersion),
 		))
 
+		return loadCatalogs()
+
 	},
 }
 
+// loadCatalogs extends the embedded catalog with the one in the user config dir and the one passed explicitly
+func loadCatalogs() error {
+
+	path, err := detect.CatalogPath()
+
+	if err == nil {
+
+		catalog, err := detect.LoadCatalog(path)
+
+		switch {
+		case err == nil:
+			slog.Debug("using user catalog", slog.String("path", path))
+			detect.UseCatalog(catalog)
+		case !errors.Is(err, fs.ErrNotExist):
+			return err
+		}
+
+	}
+
+	if rootCatalog != "" {
+
+		catalog, err := detect.LoadCatalog(rootCatalog)
+
+		if err != nil {
+			return err
+		}
+
+		detect.UseCatalog(catalog)
+
+	}
+
+	return nil
+
+}
+
 func init() {
 
 	flags := rootCmd.PersistentFlags()
 
 	flags.BoolVar(&rootDebug, "debug", false, "Enable debug logging")
+	flags.StringVar(&rootCatalog, "catalog", "", "Path to a catalog extending and overriding the device performance data (no default)")
 
 }
 
@@ -2,13 +2,9 @@ package command
 
 import (
 	"context"
-	"fmt"
 	"time"
 
-	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
-	"github.com/yawn/instagpu/provider"
-	"github.com/yawn/instagpu/provider/aws"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -21,24 +17,13 @@ var setupCmd = &cobra.Command{
 	Short: "Setup foundational infrastructure for the selected provider(s)",
 	RunE: func(cmd *cobra.Command, args []string) error {
 
-		ctx, _ := context.WithTimeout(context.Background(), setupTimeout)
+		ctx, cancel := context.WithTimeout(context.Background(), setupTimeout)
+		defer cancel()
 
-		var providers []provider.Provider
+		providers, err := configure(ctx, setupProviderAWS)
 
-		if setupProviderAWS {
-
-			provider, err := aws.New(ctx)
-
-			if err != nil {
-				return errors.Wrapf(err, "failed to configure aws")
-			}
-
-			providers = append(providers, provider)
-
-		}
-
-		if len(providers) == 0 {
-			return fmt.Errorf("no providers selected")
+		if err != nil {
+			return err
 		}
 
 		wg, ctx := errgroup.WithContext(ctx)
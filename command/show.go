@@ -4,21 +4,35 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"os"
+	"strings"
 	"time"
 
-	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"github.com/yawn/instagpu/database"
 	"github.com/yawn/instagpu/database/filter"
-	"github.com/yawn/instagpu/provider"
-	"github.com/yawn/instagpu/provider/aws"
+	"github.com/yawn/instagpu/database/output"
+	"github.com/yawn/instagpu/database/score"
+	"github.com/yawn/instagpu/detect"
 )
 
-var showCache bool
-var showDatabasePath string
+// whereHelp lists the fields of where expressions instead of showing instances
+const whereHelp = "help"
+
+var showCache cache
+var showCheapestZone bool
+var showOptions database.Options
+var showOutput output.Options
 var showFilterMaxResults uint16
+var showIncludeUnscored bool
+var showLatencyFrom string
+var showLatencyProbe string
 var showProviderAWS bool
+var showScore string
+var showSort string
 var showTimeout time.Duration
+var showVantage string
+var showWhere string
 
 var showCmd = &cobra.Command{
 
@@ -26,24 +40,29 @@ var showCmd = &cobra.Command{
 	Short: "Show a list of candiate instances",
 	RunE: func(cmd *cobra.Command, args []string) error {
 
-		ctx, _ := context.WithTimeout(context.Background(), showTimeout)
+		if showWhere == whereHelp {
 
-		var providers []provider.Provider
+			for _, field := range filter.Fields() {
+				fmt.Println(field)
+			}
 
-		if showProviderAWS {
+			return nil
 
-			provider, err := aws.New(ctx)
+		}
 
-			if err != nil {
-				return errors.Wrapf(err, "failed to configure aws")
-			}
+		ctx, cancel := context.WithTimeout(context.Background(), showTimeout)
+		defer cancel()
 
-			providers = append(providers, provider)
+		providers, err := configure(ctx, showProviderAWS)
 
+		if err != nil {
+			return err
 		}
 
-		if len(providers) == 0 {
-			return fmt.Errorf("no providers selected")
+		writer, err := output.New(&showOutput)
+
+		if err != nil {
+			return err
 		}
 
 		var filters []filter.Filter
@@ -57,31 +76,65 @@ var showCmd = &cobra.Command{
 
 		}
 
-		var (
-			db  database.Database
-			err error
-		)
+		if showWhere != "" {
+
+			where, err := filter.Where(showWhere)
+
+			if err != nil {
+				return err
+			}
+
+			filters = append(filters, where)
 
-		if showCache {
-			db, err = database.Load(showDatabasePath)
 		}
 
-		if !showCache || err != nil {
+		showOptions.Prober, err = detect.LookupProber(showLatencyProbe)
 
-			db, err = database.New(ctx)
+		if err != nil {
+			return err
+		}
 
-			if err != nil {
-				return errors.Wrapf(err, "failed to initialize database")
-			}
+		showOptions.Regions = filter.Regions()
+
+		db, err := open(ctx, &showCache, &showOptions, providers...)
 
+		if err != nil {
+			return err
 		}
 
-		results := db.Filter(showFilterMaxResults, filters...)
+		if err := vantage(ctx, &showCache, db, showLatencyFrom, showVantage); err != nil {
+			return err
+		}
+
+		scorer, err := score.Parse(showScore)
 
-		for _, result := range results {
-			fmt.Println(result)
+		if err != nil {
+			return err
 		}
 
+		order, err := database.ParseOrder(showSort)
+
+		if err != nil {
+			return err
+		}
+
+		results := db.Filter(&database.Query{
+			Cheapest:        showCheapestZone,
+			Filters:         filters,
+			IncludeUnscored: showIncludeUnscored,
+			Max:             showFilterMaxResults,
+			Order:           order,
+			Scorer:          scorer,
+			Zones:           filter.Zones(),
+		})
+
+		if err := writer.Write(os.Stdout, results); err != nil {
+			return err
+		}
+
+		summarize(os.Stderr, db)
+		diagnose(os.Stderr, db, scorer, showIncludeUnscored)
+
 		return nil
 
 	},
@@ -91,10 +144,28 @@ func init() {
 
 	flags := showCmd.Flags()
 
-	flags.BoolVar(&showCache, "cache", true, "Enable caching")
+	flags.BoolVar(&showCache.enabled, "cache", true, "Enable caching")
+	flags.BoolVar(&showCheapestZone, "cheapest-zone", false, "Rank by the prices of the cheapest availability zone of each instance instead of the ones across zones")
+	flags.BoolVar(&showIncludeUnscored, "include-unscored", false, "Include instances lacking performance data for the scorer, ranked after all scored ones")
+	flags.BoolVar(&showCache.refresh, "refresh", false, "Ignore a cached database and fetch a fresh one")
+	flags.BoolVar(&showOptions.Strict, "strict", false, "Abort if any provider, region or instance fails to fetch")
 	flags.BoolVar(&showProviderAWS, "provider-aws", true, "Enable AWS")
+	flags.DurationVar(&showCache.maxAge, "cache-max-age", 24*time.Hour, "Maximum age of a cached database before it is refetched")
+	flags.DurationVar(&showOptions.Window, "price-window", database.WINDOW, "Look-back window of the spot price history the price statistics are computed over")
 	flags.DurationVar(&showTimeout, "timeout", 30*time.Second, "Timeout for all API operations")
-	flags.StringVar(&showDatabasePath, "database-path", "database.json", "Path to the pricing database, for caching")
+	flags.IntVar(&showOptions.Concurrency, "concurrency", database.CONCURRENCY, "Maximum concurrent API calls per provider")
+	flags.IntVar(&showOptions.Samples, "latency-samples", detect.SAMPLES, "Latency samples per region")
+	flags.IntVar(&showOptions.RegionConcurrency, "region-concurrency", database.REGION_CONCURRENCY, "Maximum concurrent API calls per provider region")
+	flags.StringVar(&showOutput.Columns, "columns", output.COLUMNS, "Comma-separated columns of table and csv output: "+strings.Join(output.Columns(), ", "))
+	flags.StringVar(&showCache.path, "database-path", "database.json", "Path to the pricing database, for caching")
+	flags.StringVar(&showLatencyFrom, "latency-from", "", "Imports latencies measured elsewhere (e.g. by \"instagpu latency\" on a CI runner) from a file or http(s) URL, caching them under their vantage point name (no default)")
+	flags.StringVar(&showLatencyProbe, "latency-probe", detect.PROBE, "Latency probe: icmp (needs privileges), tcp (connect to port 443), https (connect and TLS handshake) or auto, falling back in this order")
+	flags.StringVar(&showOutput.Format, "output", output.TEXT, "Output format: "+strings.Join(output.Formats(), ", "))
+	flags.StringVar(&showScore, "score", score.DEFAULT, "Scores by performance per USD / h: fp32, fp16, bf16, fp8, vram, bandwidth or a weighted composite like \"bf16=0.7,vram=0.3\"")
+	flags.StringVar(&showSort, "sort", database.SORT, "Sorts by comma-separated keys, best first unless prefixed with \"-\": "+strings.Join(database.Keys(), ", "))
+	flags.StringVar(&showOutput.Template, "template", "", "Go template rendered per result for template output, e.g. '{{.Prices.Instance.Name}} {{.Prices.Avg}}' (no default)")
+	flags.StringVar(&showVantage, "vantage", "", "Ranks by latencies from this cached vantage point, e.g. \""+database.LOCAL+"\" for the ones measured here (defaults to the one imported by --latency-from, else "+database.LOCAL+")")
+	flags.StringVar(&showWhere, "where", "", "Filters by an expression like 'gpu.vendor in (\"NVIDIA\") && gpu.memory >= 48 && (price.avg < 2 || region.latency < 40)' over the gpu, instance, price, region and zone fields listed by --where "+whereHelp+", combined with all other filters (no default)")
 	flags.Uint16Var(&showFilterMaxResults, "filter-max-results", 10, "Filters by maximum results")
 
 	for _, flag := range filter.Flags {
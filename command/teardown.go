@@ -0,0 +1,51 @@
+package command
+
+import (
+	"context"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/sync/errgroup"
+)
+
+var teardownProviderAWS bool
+var teardownTimeout time.Duration
+
+var teardownCmd = &cobra.Command{
+
+	Use:   "teardown",
+	Short: "Remove foundational infrastructure for the selected provider(s)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		ctx, cancel := context.WithTimeout(context.Background(), teardownTimeout)
+		defer cancel()
+
+		providers, err := configure(ctx, teardownProviderAWS)
+
+		if err != nil {
+			return err
+		}
+
+		wg, ctx := errgroup.WithContext(ctx)
+
+		for _, provider := range providers {
+			wg.Go(func() error {
+				return provider.Teardown(ctx)
+			})
+		}
+
+		return wg.Wait()
+
+	},
+}
+
+func init() {
+
+	flags := teardownCmd.Flags()
+
+	flags.BoolVar(&teardownProviderAWS, "provider-aws", true, "Enable AWS")
+	flags.DurationVar(&teardownTimeout, "timeout", 5*time.Minute, "Timeout for all API operations")
+
+	rootCmd.AddCommand(teardownCmd)
+
+}
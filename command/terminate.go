@@ -0,0 +1,106 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/yawn/instagpu/detect"
+	"golang.org/x/sync/errgroup"
+)
+
+var terminateAll bool
+var terminateOlderThan time.Duration
+var terminateProviderAWS bool
+var terminateTimeout time.Duration
+
+var terminateCmd = &cobra.Command{
+
+	Use:   "terminate [<id>...]",
+	Short: "Terminate instances launched by instagpu",
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		if len(args) == 0 && !terminateAll && terminateOlderThan == 0 {
+			return fmt.Errorf("no instances selected - pass ids, --all or --older-than")
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), terminateTimeout)
+		defer cancel()
+
+		providers, err := configure(ctx, terminateProviderAWS)
+
+		if err != nil {
+			return err
+		}
+
+		machines, err := machines(ctx, providers)
+
+		if err != nil {
+			return err
+		}
+
+		for _, id := range args {
+
+			if !slices.ContainsFunc(machines, func(machine *detect.Machine) bool {
+				return machine.ID == id
+			}) {
+				return fmt.Errorf("no instance %q launched by %s", id, app)
+			}
+
+		}
+
+		now := time.Now()
+
+		machines = slices.DeleteFunc(machines, func(machine *detect.Machine) bool {
+
+			if len(args) > 0 && !slices.Contains(args, machine.ID) {
+				return true
+			}
+
+			return machine.Uptime(now) < terminateOlderThan
+
+		})
+
+		wg, ctx := errgroup.WithContext(ctx)
+
+		for _, machine := range machines {
+
+			provider, err := lookup(providers, machine.Region.Provider)
+
+			if err != nil {
+				return err
+			}
+
+			wg.Go(func() error {
+
+				if err := provider.Terminate(ctx, machine); err != nil {
+					return err
+				}
+
+				fmt.Println(machine)
+
+				return nil
+
+			})
+
+		}
+
+		return wg.Wait()
+
+	},
+}
+
+func init() {
+
+	flags := terminateCmd.Flags()
+
+	flags.BoolVar(&terminateAll, "all", false, "Terminate all instances")
+	flags.BoolVar(&terminateProviderAWS, "provider-aws", true, "Enable AWS")
+	flags.DurationVar(&terminateOlderThan, "older-than", 0, "Terminate all instances with an uptime above this duration (no default)")
+	flags.DurationVar(&terminateTimeout, "timeout", time.Minute, "Timeout for all API operations")
+
+	rootCmd.AddCommand(terminateCmd)
+
+}
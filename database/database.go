@@ -6,44 +6,187 @@ package database
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"path/filepath"
 	"slices"
 	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/yawn/instagpu/database/filter"
+	"github.com/yawn/instagpu/database/score"
 	"github.com/yawn/instagpu/detect"
 	"github.com/yawn/instagpu/provider"
 	"golang.org/x/sync/errgroup"
 )
 
-type Database []*detect.Prices
+// VERSION is the schema version of saved databases
+const VERSION = 4
+
+// WINDOW is the default look-back window for price histories
+const WINDOW = 7 * 24 * time.Hour
+
+type Database struct {
+	Build     string                               `json:"build"`              // version of instagpu that fetched the database
+	Excluded  map[string][]string                  `json:"excluded,omitempty"` // region names per provider excluded from fetching
+	Failures  []*Failure                           `json:"failures,omitempty"`
+	Fetched   time.Time                            `json:"fetched"`
+	Prices    []*detect.Prices                     `json:"prices"`
+	Providers []string                             `json:"providers"`
+	Regions   []string                             `json:"regions"`            // provider-region pairs
+	Vantages  map[string]map[string]detect.Latency `json:"vantages,omitempty"` // latencies by vantage point and provider-region pair
+	Version   uint                                 `json:"version"`
+	Warnings  []*Warning                           `json:"warnings,omitempty"`
+	Window    time.Duration                        `json:"window"`
+
+	migrated bool // loaded from an older version
+}
+
+// Regions selects regions by name
+type Regions func(name string) bool
+
+type Options struct {
+	Build             string        // version of instagpu, recorded in the database
+	Concurrency       int           // maximum concurrent calls per provider, defaults to CONCURRENCY
+	Prober            detect.Prober // latency prober, defaults to detect.PROBE
+	RegionConcurrency int           // maximum concurrent calls per provider region, defaults to REGION_CONCURRENCY
+	Regions           Regions       // selects the regions to fetch, defaults to all
+	Samples           int           // latency samples per region, defaults to detect.SAMPLES
+	Strict            bool          // abort on the first failure instead of recording it
+	Window            time.Duration // look-back window for price histories, defaults to WINDOW
+}
 
-func New(ctx context.Context, providers ...provider.Provider) (Database, error) {
+func New(ctx context.Context, options *Options, providers ...provider.Provider) (*Database, error) {
 
 	wg, ctx := errgroup.WithContext(ctx)
 
 	var (
+		local   = make(map[string]detect.Latency)
 		logger  = slog.Default()
 		mutex   sync.Mutex
 		results []*detect.Prices
+		window  = options.Window
+		db      = &Database{
+			Build:   options.Build,
+			Version: VERSION,
+		}
 	)
 
+	if window == 0 {
+		window = WINDOW
+	}
+
+	prober, samples := options.Prober, options.Samples
+
+	if prober == nil {
+		prober = detect.Probers[detect.PROBE]
+	}
+
+	if samples <= 0 {
+		samples = detect.SAMPLES
+	}
+
+	db.Window = window
+
+	// fail records a failure, or returns it when running strict
+	fail := func(logger *slog.Logger, failure *Failure, err error) error {
+
+		if options.Strict {
+			return err
+		}
+
+		failure.Error = err.Error()
+
+		logger.Warn("failed to fetch",
+			slog.String("stage", failure.Stage),
+			slog.String("error", failure.Error),
+		)
+
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		db.Failures = append(db.Failures, failure)
+
+		return nil
+
+	}
+
+	// warn records instances skipped by a provider
+	warn := func(logger *slog.Logger, name, region string, skipped []*provider.Skipped) {
+
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		for _, skipped := range skipped {
+
+			logger.Warn("instance skipped",
+				slog.String("instance", skipped.Instance),
+				slog.String("reason", skipped.Reason),
+			)
+
+			db.Warnings = append(db.Warnings, &Warning{
+				Instance: skipped.Instance,
+				Provider: name,
+				Reason:   skipped.Reason,
+				Region:   region,
+			})
+
+		}
+
+	}
+
 	for _, provider := range providers {
 
-		logger = logger.With(
+		logger := logger.With(
 			slog.String("provider", provider.Name()),
 		)
 
-		regions, err := provider.Regions(ctx)
+		db.Providers = append(db.Providers, provider.Name())
+
+		var (
+			limiter = newLimiter(options.Concurrency, options.RegionConcurrency)
+			regions []*detect.Region
+		)
+
+		err := limiter.do(ctx, "", func() (err error) {
+			regions, err = provider.Regions(ctx)
+			return
+		})
 
 		if err != nil {
-			return nil, err
+
+			if err := fail(logger, &Failure{
+				Provider: provider.Name(),
+				Stage:    StageRegions,
+			}, err); err != nil {
+				return nil, err
+			}
+
+			continue
+
 		}
 
 		for _, region := range regions {
 
+			if options.Regions != nil && !options.Regions(region.Name) {
+
+				logger.Debug("region excluded", slog.String("region", region.Name))
+
+				if db.Excluded == nil {
+					db.Excluded = make(map[string][]string)
+				}
+
+				db.Excluded[provider.Name()] = append(db.Excluded[provider.Name()], region.Name)
+
+				continue
+
+			}
+
+			db.Regions = append(db.Regions, pair(provider.Name(), region.Name))
+
 			logger := logger.With(
 				slog.String("region", region.Name),
 			)
@@ -52,7 +195,20 @@ func New(ctx context.Context, providers ...provider.Provider) (Database, error)
 
 				logger.Debug("measuring latency for region")
 
-				return region.MeasureLatency(ctx)
+				if err := region.MeasureLatency(ctx, prober, samples); err != nil {
+					return fail(logger, &Failure{
+						Provider: provider.Name(),
+						Region:   region.Name,
+						Stage:    StageLatency,
+					}, err)
+				}
+
+				mutex.Lock()
+				defer mutex.Unlock()
+
+				local[pair(provider.Name(), region.Name)] = region.Latency
+
+				return nil
 
 			})
 
@@ -60,10 +216,25 @@ func New(ctx context.Context, providers ...provider.Provider) (Database, error)
 
 				logger.Debug("gathering instances")
 
-				instances, err := provider.Instances(ctx, region)
+				var instances []*detect.Instance
+
+				err := limiter.do(ctx, region.Name, func() error {
+
+					found, skipped, err := provider.Instances(ctx, region)
+
+					instances = found
+					warn(logger, provider.Name(), region.Name, skipped)
 
-				if err != nil {
 					return err
+
+				})
+
+				if err != nil {
+					return fail(logger, &Failure{
+						Provider: provider.Name(),
+						Region:   region.Name,
+						Stage:    StageInstances,
+					}, err)
 				}
 
 				for _, instance := range instances {
@@ -78,10 +249,20 @@ func New(ctx context.Context, providers ...provider.Provider) (Database, error)
 
 						logger.Debug("gathering prices")
 
-						prices, err := provider.Prices(ctx, region, instance)
+						var prices *detect.Prices
+
+						err := limiter.do(ctx, region.Name, func() (err error) {
+							prices, err = provider.Prices(ctx, region, instance, window)
+							return
+						})
 
 						if err != nil {
-							return err
+							return fail(logger, &Failure{
+								Instance: instance.Name,
+								Provider: provider.Name(),
+								Region:   region.Name,
+								Stage:    StagePrices,
+							}, err)
 						}
 
 						if prices == nil {
@@ -114,45 +295,105 @@ func New(ctx context.Context, providers ...provider.Provider) (Database, error)
 		return nil, err
 	}
 
-	return results, nil
+	if len(results) == 0 && len(db.Failures) > 0 {
+		return nil, fmt.Errorf("failed to fetch any prices: %s", db.Failures[0].Error)
+	}
+
+	db.Fetched = time.Now()
+	db.Prices = results
+
+	db.AddVantagePoint(&VantagePoint{
+		Name:    LOCAL,
+		Regions: local,
+	})
+
+	return db, nil
+
+}
 
+// Query selects and ranks results from a database
+type Query struct {
+	Cheapest        bool // rank by the prices of the cheapest availability zone instead of the ones across zones
+	Filters         []filter.Filter
+	IncludeUnscored bool                         // rank entries lacking performance data after all scored ones instead of excluding them
+	Max             uint16                       // maximum number of results
+	Order           Order                        // sort order, defaults to SORT
+	Scorer          score.Scorer                 // scorer used for ranking
+	Zones           func(zone *detect.Zone) bool // availability zones to choose the cheapest one from, defaults to all
 }
 
-func (d Database) Filter(max uint16, filters ...filter.Filter) []*Result {
+func (d *Database) Filter(query *Query) []*Result {
 
 	var (
-		results []*Result
-		top     float64
+		results  []*Result
+		unscored []*Result
+		top      float64
 	)
 
-	for _, prices := range d {
+	for _, prices := range d.Prices {
 
-		results = append(results, &Result{
+		if query.Zones != nil {
+			prices = prices.InZones(query.Zones)
+		}
+
+		if zone := prices.Cheapest(); query.Cheapest && zone != nil {
+			prices = prices.AtZone(zone)
+		}
+
+		value, ok := query.Scorer.Score(prices)
+
+		result := &Result{
 			Prices: prices,
-			Score:  prices.PTGPIndex(),
-		})
+			Score:  value,
+			Scored: ok,
+			Scorer: query.Scorer.Name(),
+		}
+
+		if !ok {
+
+			slog.Debug("unscored, lacking performance data",
+				slog.String("instance", prices.Instance.Name),
+				slog.String("scorer", query.Scorer.Name()),
+			)
+
+			unscored = append(unscored, result)
+
+			continue
+
+		}
+
+		results = append(results, result)
 
 	}
 
-	if len(results) == 0 {
-		return nil
+	for _, result := range results {
+		top = max(top, result.Score)
 	}
 
-	slices.SortFunc(results, func(a, b *Result) int {
-		return int(b.Score - a.Score)
-	})
+	for _, result := range results {
+		result.Relative = result.Score / top
+	}
+
+	if query.IncludeUnscored {
+		results = append(results, unscored...)
+	}
 
-	top = results[0].Score
+	order := query.Order
+
+	if len(order) == 0 {
+		order = Order{{Name: SORT}}
+	}
+
+	slices.SortStableFunc(results, order.compare)
 
 	for idx, result := range results {
 		result.Index = idx
 		result.IndexMax = len(results) - 1
-		result.Relative = result.Score / top
 	}
 
 	results = slices.DeleteFunc(results, func(result *Result) bool {
 
-		for _, filter := range filters {
+		for _, filter := range query.Filters {
 
 			if !filter(result.Prices) {
 				return true
@@ -164,28 +405,96 @@ func (d Database) Filter(max uint16, filters ...filter.Filter) []*Result {
 
 	})
 
-	return results[:min(int(max), len(results))]
+	return results[:min(int(query.Max), len(results))]
+
+}
+
+// Unscored returns the instance names lacking performance data for scorer, keyed by the catalog tag of
+// their accelerator
+func (d *Database) Unscored(scorer score.Scorer) map[string][]string {
+
+	unscored := make(map[string][]string)
+
+	for _, prices := range d.Prices {
+
+		if _, ok := scorer.Score(prices); ok {
+			continue
+		}
+
+		tag := prices.Instance.GPU.Tag()
+
+		if !slices.Contains(unscored[tag], prices.Instance.Name) {
+			unscored[tag] = append(unscored[tag], prices.Instance.Name)
+		}
+
+	}
+
+	return unscored
+
+}
+
+// Covers reports if the database contains all regions selected by regions, i.e. none of them were excluded
+// from fetching
+func (d *Database) Covers(regions Regions) bool {
+
+	for _, names := range d.Excluded {
+
+		for _, name := range names {
+
+			if regions == nil || regions(name) {
+				return false
+			}
+
+		}
+
+	}
+
+	return true
+
+}
+
+// IsMigrated reports if the database was loaded from an older version, lacking data a migration cannot rebuild
+func (d *Database) IsMigrated() bool {
+	return d.migrated
+}
 
+// IsStale reports if the database was fetched longer ago than maxAge
+func (d *Database) IsStale(maxAge time.Duration) bool {
+	return time.Since(d.Fetched) > maxAge
 }
 
-func (d Database) Save(path string) error {
+// Save atomically writes the database to path
+func (d *Database) Save(path string) error {
 
-	fh, err := os.Create(path)
+	fh, err := os.CreateTemp(filepath.Dir(path), fmt.Sprintf(".%s.*", filepath.Base(path)))
 
 	if err != nil {
 		return errors.Wrapf(err, "failed to save to file %q", path)
 	}
 
-	defer fh.Close()
+	defer os.Remove(fh.Name())
 
 	enc := json.NewEncoder(fh)
 	enc.SetIndent("", "\t")
 
-	return enc.Encode(d)
+	if err := enc.Encode(d); err != nil {
+		fh.Close()
+		return errors.Wrapf(err, "failed to encode database")
+	}
+
+	if err := fh.Close(); err != nil {
+		return errors.Wrapf(err, "failed to save to file %q", path)
+	}
+
+	if err := os.Rename(fh.Name(), path); err != nil {
+		return errors.Wrapf(err, "failed to save to file %q", path)
+	}
+
+	return nil
 
 }
 
-func Load(path string) (Database, error) {
+func Load(path string) (*Database, error) {
 
 	fh, err := os.Open(path)
 
@@ -195,14 +504,26 @@ func Load(path string) (Database, error) {
 
 	defer fh.Close()
 
-	dec := json.NewDecoder(fh)
+	raw, err := io.ReadAll(fh)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read file %q", path)
+	}
+
+	raw, migrated, err := migrate(raw)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "incompatible database in file %q", path)
+	}
 
 	var db Database
 
-	if err := dec.Decode(&db); err != nil {
+	if err := json.Unmarshal(raw, &db); err != nil {
 		return nil, errors.Wrapf(err, "corrupt database in file %q", path)
 	}
 
-	return db, nil
+	db.migrated = migrated
+
+	return &db, nil
 
 }
@@ -0,0 +1,446 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yawn/instagpu/database/filter"
+	"github.com/yawn/instagpu/database/score"
+	"github.com/yawn/instagpu/detect"
+	"github.com/yawn/instagpu/provider"
+	"github.com/yawn/instagpu/provider/fake"
+)
+
+// counting is a fake provider counting in-flight calls overall and per region
+type counting struct {
+	*fake.Fake
+	inflight map[string]int
+	max      map[string]int
+	mutex    sync.Mutex
+	throttle map[string]int // remaining throttled calls per prices key
+}
+
+func newCounting(regions, instances int) *counting {
+
+	f := fake.New()
+
+	for r := range regions {
+
+		region := fmt.Sprintf("r%d", r)
+
+		f.AddRegion(region)
+
+		for i := range instances {
+			f.AddInstance(region, &detect.Instance{
+				GPU:  &detect.GPU{},
+				Name: fmt.Sprintf("i%d", i),
+			}, &detect.Prices{AvailabilityZones: 1, Avg: 1})
+		}
+
+	}
+
+	return &counting{
+		Fake:     f,
+		inflight: make(map[string]int),
+		max:      make(map[string]int),
+		throttle: make(map[string]int),
+	}
+
+}
+
+func (c *counting) enter(region string) func() {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, key := range []string{"", region} {
+		c.inflight[key]++
+		c.max[key] = max(c.max[key], c.inflight[key])
+	}
+
+	return func() {
+
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+
+		c.inflight[""]--
+		c.inflight[region]--
+
+	}
+
+}
+
+func (c *counting) Instances(ctx context.Context, region *detect.Region) ([]*detect.Instance, []*provider.Skipped, error) {
+	defer c.enter(region.Name)()
+	return c.Fake.Instances(ctx, region)
+}
+
+func (c *counting) Prices(ctx context.Context, region *detect.Region, instance *detect.Instance, window time.Duration) (*detect.Prices, error) {
+
+	defer c.enter(region.Name)()
+
+	time.Sleep(2 * time.Millisecond)
+
+	key := fake.Key("prices", region.Name, instance.Name)
+
+	c.mutex.Lock()
+
+	if c.throttle[key] > 0 {
+		c.throttle[key]--
+		c.mutex.Unlock()
+		return nil, provider.Throttled(fmt.Errorf("slow down"))
+	}
+
+	c.mutex.Unlock()
+
+	return c.Fake.Prices(ctx, region, instance, window)
+
+}
+
+func TestNewConcurrency(t *testing.T) {
+
+	assert := assert.New(t)
+
+	p := newCounting(4, 20)
+
+	db, err := New(context.Background(), &Options{
+		Concurrency:       5,
+		Prober:            fake.Prober(time.Millisecond),
+		RegionConcurrency: 2,
+	}, p)
+
+	assert.NoError(err)
+	assert.Len(db.Prices, 80)
+
+	assert.LessOrEqual(p.max[""], 5)
+
+	for i := range 4 {
+		assert.LessOrEqual(p.max[fmt.Sprintf("r%d", i)], 2)
+	}
+
+}
+
+func TestNewFailures(t *testing.T) {
+
+	var (
+		p      = fake.Sample()
+		prober = fake.Prober(10 * time.Millisecond)
+	)
+
+	p.Fail(fake.Key("instances", "us-east-1"), fmt.Errorf("access denied"))
+
+	t.Run("partial", func(t *testing.T) {
+
+		assert := assert.New(t)
+
+		db, err := New(context.Background(), &Options{Prober: prober}, p)
+
+		assert.NoError(err)
+		assert.Len(db.Prices, 2, "only eu-west-1, skipping the instance not available as spot")
+		assert.Equal([]string{fake.NAME}, db.Providers)
+		assert.Equal([]string{"fake-eu-west-1", "fake-us-east-1"}, db.Regions)
+
+		assert.Equal([]*Failure{
+			{
+				Error:    "access denied",
+				Provider: fake.NAME,
+				Region:   "us-east-1",
+				Stage:    StageInstances,
+			},
+		}, db.Failures)
+
+	})
+
+	t.Run("strict", func(t *testing.T) {
+
+		_, err := New(context.Background(), &Options{Prober: prober, Strict: true}, p)
+
+		assert.ErrorContains(t, err, "access denied")
+
+	})
+
+	t.Run("total", func(t *testing.T) {
+
+		p.Fail(fake.Key("regions"), fmt.Errorf("access denied"))
+
+		_, err := New(context.Background(), &Options{Prober: prober}, p)
+
+		assert.ErrorContains(t, err, "failed to fetch any prices")
+
+	})
+
+}
+
+func TestNewRegions(t *testing.T) {
+
+	assert := assert.New(t)
+
+	p := fake.Sample()
+
+	p.Fail(fake.Key("instances", "us-east-1"), fmt.Errorf("must never be queried"))
+
+	db, err := New(context.Background(), &Options{
+		Prober: fake.Prober(time.Millisecond),
+		Regions: func(name string) bool {
+			return name != "us-east-1"
+		},
+	}, p)
+
+	if !assert.NoError(err) {
+		return
+	}
+
+	assert.Equal([]string{"fake-eu-west-1"}, db.Regions)
+	assert.Equal(map[string][]string{fake.NAME: {"us-east-1"}}, db.Excluded)
+
+	for _, failure := range db.Failures {
+		assert.NotEqual("us-east-1", failure.Region)
+	}
+
+	for _, prices := range db.Prices {
+		assert.Equal("eu-west-1", prices.Instance.Region.Name)
+	}
+
+	assert.True(db.Covers(func(name string) bool { return name == "eu-west-1" }))
+	assert.False(db.Covers(func(name string) bool { return name == "us-east-1" }))
+	assert.False(db.Covers(nil), "all regions selected")
+
+}
+
+func TestNewThrottled(t *testing.T) {
+
+	assert := assert.New(t)
+
+	p := newCounting(1, 3)
+
+	p.throttle[fake.Key("prices", "r0", "i1")] = 2
+
+	db, err := New(context.Background(), &Options{Prober: fake.Prober(time.Millisecond)}, p)
+
+	assert.NoError(err)
+	assert.Len(db.Prices, 3)
+
+	for _, failure := range db.Failures {
+		assert.NotEqual(StagePrices, failure.Stage)
+	}
+
+}
+
+// candidate returns prices for an instance with a single GPU of fp32 TFLOPS, named after the instance
+func candidate(region, name string, fp32 *float64, avg float64) *detect.Prices {
+	return &detect.Prices{
+		Avg: avg,
+		Instance: &detect.Instance{
+			GPU: &detect.GPU{
+				FP32:   fp32,
+				Name:   name,
+				Vendor: "Vendor",
+			},
+			Name: name,
+			Region: &detect.Region{
+				Name:     region,
+				Provider: "fake",
+			},
+		},
+	}
+}
+
+func TestFilterOrder(t *testing.T) {
+
+	tflops := func(v float64) *float64 {
+		return &v
+	}
+
+	var (
+		slow = candidate("r1", "slow", tflops(1), 1)   // 1.0 / $
+		fast = candidate("r0", "fast", tflops(1.5), 1) // 1.5 / $
+		near = candidate("r0", "near", tflops(1.4), 1) // 1.4 / $, within a truncated int of fast
+		tie1 = candidate("r1", "tie", tflops(2.4), 2)  // 1.2 / $
+		tie0 = candidate("r0", "tie", tflops(1.2), 1)  // 1.2 / $
+		db   = &Database{
+			Prices: []*detect.Prices{slow, tie1, near, tie0, fast},
+		}
+		scorer = score.Scorers["fp32"]
+	)
+
+	slow.Instance.Region.Latency.Avg = 10
+	tie1.Instance.Region.Latency.Avg = 50
+
+	names := func(results []*Result) []string {
+
+		var names []string
+
+		for _, result := range results {
+			names = append(names, result.Prices.Instance.Region.Name+"/"+result.Prices.Instance.Name)
+		}
+
+		return names
+
+	}
+
+	t.Run("score", func(t *testing.T) {
+
+		assert := assert.New(t)
+
+		results := db.Filter(&Query{
+			Max:    10,
+			Scorer: scorer,
+		})
+
+		assert.Equal([]string{"r0/fast", "r0/near", "r0/tie", "r1/tie", "r1/slow"}, names(results), "ties broken by region")
+
+		for idx, result := range results {
+			assert.Equal(idx, result.Index)
+			assert.Equal(4, result.IndexMax)
+		}
+
+		assert.InDelta(1.0, results[0].Relative, 1e-9)
+		assert.InDelta(1.4/1.5, results[1].Relative, 1e-9)
+		assert.InDelta(1/1.5, results[4].Relative, 1e-9)
+
+	})
+
+	t.Run("keys", func(t *testing.T) {
+
+		assert := assert.New(t)
+
+		order, err := ParseOrder("price,-latency,score")
+
+		assert.NoError(err)
+
+		results := db.Filter(&Query{
+			Max:    10,
+			Order:  order,
+			Scorer: scorer,
+		})
+
+		assert.Equal([]string{"r1/slow", "r0/fast", "r0/near", "r0/tie", "r1/tie"}, names(results), "unmeasured latency last even if reversed")
+		assert.InDelta(1/1.5, results[0].Relative, 1e-9, "relative to best score regardless of order")
+		assert.InDelta(1.0, results[1].Relative, 1e-9)
+		assert.Equal(4, results[4].Index)
+
+	})
+
+	t.Run("cheapest", func(t *testing.T) {
+
+		assert := assert.New(t)
+
+		slow.Zones = []*detect.Zone{
+			{Avg: 1.5, Name: "r1b"},
+			{Avg: 0.5, Name: "r1a"},
+		}
+
+		defer func() {
+			slow.Zones = nil
+		}()
+
+		results := db.Filter(&Query{
+			Max:    10,
+			Scorer: scorer,
+		})
+
+		assert.Equal("r0/fast", names(results)[0], "regional prices by default")
+
+		results = db.Filter(&Query{
+			Cheapest: true,
+			Max:      10,
+			Scorer:   scorer,
+		})
+
+		if assert.Equal("r1/slow", names(results)[0], "2.0 / $ in r1a") {
+			assert.Equal(0.5, results[0].Prices.Avg)
+			assert.Contains(results[0].String(), "🎯 r1a 0.50 USD/h")
+		}
+
+		assert.Equal(1.0, slow.Avg, "database unchanged")
+
+		results = db.Filter(&Query{
+			Cheapest: true,
+			Max:      10,
+			Scorer:   scorer,
+			Zones: func(zone *detect.Zone) bool {
+				return zone.Name != "r1a"
+			},
+		})
+
+		if assert.Equal("r0/fast", names(results)[0], "r1a not selected") {
+			assert.Contains(results[4].String(), "🎯 r1b 1.50 USD/h")
+			assert.Len(slow.Zones, 2, "database unchanged")
+		}
+
+	})
+
+	t.Run("filtered", func(t *testing.T) {
+
+		assert := assert.New(t)
+
+		results := db.Filter(&Query{
+			Filters: []filter.Filter{
+				func(p *detect.Prices) bool { return p.Instance.Region.Name == "r1" },
+			},
+			Max:    1,
+			Scorer: scorer,
+		})
+
+		if assert.Len(results, 1) {
+			assert.Equal(3, results[0].Index, "ranks before filtering")
+			assert.Equal(4, results[0].IndexMax)
+		}
+
+	})
+
+}
+
+func TestFilterUnscored(t *testing.T) {
+
+	assert := assert.New(t)
+
+	var (
+		fp32 = 10.0
+		db   = &Database{
+			Prices: []*detect.Prices{
+				candidate("r0", "unknown-expensive", nil, 2),
+				candidate("r0", "known", &fp32, 1),
+				candidate("r0", "unknown-cheap", nil, 1),
+			},
+		}
+		scorer = score.Scorers["fp32"]
+	)
+
+	results := db.Filter(&Query{
+		Max:    10,
+		Scorer: scorer,
+	})
+
+	if assert.Len(results, 1, "unscored excluded by default") {
+		assert.True(results[0].Scored)
+		assert.Equal(0, results[0].IndexMax)
+	}
+
+	results = db.Filter(&Query{
+		IncludeUnscored: true,
+		Max:             10,
+		Scorer:          scorer,
+	})
+
+	if assert.Len(results, 3) {
+
+		assert.Equal("known", results[0].Prices.Instance.Name)
+		assert.Equal("unknown-cheap", results[1].Prices.Instance.Name, "unscored ranked after scored, ties by name")
+		assert.Equal("unknown-expensive", results[2].Prices.Instance.Name)
+		assert.False(results[2].Scored)
+		assert.Equal(2, results[2].IndexMax)
+		assert.Contains(results[2].String(), "n/a")
+
+	}
+
+	assert.Equal(map[string][]string{
+		"Vendor-unknown-cheap":     {"unknown-cheap"},
+		"Vendor-unknown-expensive": {"unknown-expensive"},
+	}, db.Unscored(scorer))
+
+}
@@ -0,0 +1,55 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// stages of fetching a database in which failures are recorded
+const (
+	StageInstances = "instances"
+	StageLatency   = "latency"
+	StagePrices    = "prices"
+	StageRegions   = "regions"
+)
+
+// Failure records a provider, region or instance that could not be fetched
+type Failure struct {
+	Error    string `json:"error"`
+	Instance string `json:"instance,omitempty"`
+	Provider string `json:"provider"`
+	Region   string `json:"region,omitempty"`
+	Stage    string `json:"stage"`
+}
+
+func (f *Failure) String() string {
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "⚠️ %s", f.Provider)
+
+	if f.Region != "" {
+		fmt.Fprintf(&b, "-%s", f.Region)
+	}
+
+	if f.Instance != "" {
+		fmt.Fprintf(&b, " 🏷️ %s", f.Instance)
+	}
+
+	fmt.Fprintf(&b, "\t%s: %s", f.Stage, f.Error)
+
+	return b.String()
+
+}
+
+// Warning records an instance a provider skipped because it could not be modelled
+type Warning struct {
+	Instance string `json:"instance"`
+	Provider string `json:"provider"`
+	Reason   string `json:"reason"`
+	Region   string `json:"region"`
+}
+
+func (w *Warning) String() string {
+	return fmt.Sprintf("ℹ️ %s-%s 🏷️ %s\tskipped: %s", w.Provider, w.Region, w.Instance, w.Reason)
+}
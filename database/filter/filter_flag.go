@@ -1,23 +1,28 @@
 package filter
 
 import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
 	"github.com/spf13/pflag"
 	"github.com/yawn/instagpu/detect"
 )
 
 type (
-	Filter                func(p *detect.Prices) bool
-	install[T comparable] func(*T, string, T, string)
+	Filter         func(p *detect.Prices) bool
+	install[T any] func(flags *pflag.FlagSet, value *T, name, usage string)
 )
 
-type filterFlag[T comparable] struct {
-	defaultValue T
-	description  string
-	filter       func(T) Filter
-	ignore       T
-	install      func(flags *pflag.FlagSet) install[T]
-	name         string
-	value        T
+type filterFlag[T any] struct {
+	description string
+	filter      func(T) Filter
+	flag        *pflag.Flag // populated by install, tracking if the flag was set
+	install     install[T]
+	name        string
+	value       T
 }
 
 func (f *filterFlag[T]) Apply() Filter {
@@ -25,13 +30,169 @@ func (f *filterFlag[T]) Apply() Filter {
 }
 
 func (f *filterFlag[T]) Install(fs *pflag.FlagSet) {
-	f.install(fs)(&f.value, f.name, f.defaultValue, f.description)
+	f.install(fs, &f.value, f.name, f.description)
+	f.flag = fs.Lookup(f.name)
 }
 
 func (f *filterFlag[T]) IsSet() bool {
-	return f.value != f.ignore
+	return f.flag != nil && f.flag.Changed
 }
 
 func (f *filterFlag[T]) Name() string {
 	return f.name
 }
+
+// Range is an inclusive range of numbers, parsed from "min:max" with either bound optional - a single number
+// is the lower bound or, for upper ranges, the upper bound
+type Range struct {
+	Max   *float64
+	Min   *float64
+	upper bool
+}
+
+// bounded installs a range flag, interpreting single numbers as upper bound if upper is true
+func bounded(upper bool) install[Range] {
+	return func(flags *pflag.FlagSet, value *Range, name, usage string) {
+
+		*value = Range{upper: upper}
+
+		flags.Var(value, name, usage)
+
+	}
+}
+
+// statistic installs a statistic range flag, interpreting single numbers as upper bound if upper is true
+func statistic(upper bool) install[Statistic] {
+	return func(flags *pflag.FlagSet, value *Statistic, name, usage string) {
+
+		*value = Statistic{
+			Name:  "avg",
+			Range: Range{upper: upper},
+		}
+
+		flags.Var(value, name, usage)
+
+	}
+}
+
+// slice installs a flag accepting comma-separated or repeated strings
+func slice(flags *pflag.FlagSet, value *[]string, name, usage string) {
+	flags.StringSliceVar(value, name, nil, usage)
+}
+
+// Contains reports if v lies within the range
+func (r *Range) Contains(v float64) bool {
+	return (r.Min == nil || v >= *r.Min) && (r.Max == nil || v <= *r.Max)
+}
+
+func (r *Range) Set(value string) error {
+
+	parse := func(value string) (*float64, error) {
+
+		if value = strings.TrimSpace(value); value == "" {
+			return nil, nil
+		}
+
+		v, err := strconv.ParseFloat(value, 64)
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", value)
+		}
+
+		return &v, nil
+
+	}
+
+	lower, upper, ok := strings.Cut(value, ":")
+
+	if !ok && r.upper {
+		lower, upper = "", lower
+	}
+
+	min, err := parse(lower)
+
+	if err != nil {
+		return err
+	}
+
+	max, err := parse(upper)
+
+	if err != nil {
+		return err
+	}
+
+	if min == nil && max == nil {
+		return fmt.Errorf("range %q lacks both bounds, expected \"min:max\", \"min:\" or \":max\"", value)
+	}
+
+	if min != nil && max != nil && *min > *max {
+		return fmt.Errorf("range %q has a lower bound above its upper bound", value)
+	}
+
+	r.Max, r.Min = max, min
+
+	return nil
+
+}
+
+func (r *Range) String() string {
+
+	format := func(v *float64) string {
+
+		if v == nil {
+			return ""
+		}
+
+		return strconv.FormatFloat(*v, 'f', -1, 64)
+
+	}
+
+	if r.Min == nil && r.Max == nil {
+		return ""
+	}
+
+	return format(r.Min) + ":" + format(r.Max)
+
+}
+
+func (r *Range) Type() string {
+	return "range"
+}
+
+// Statistic is a range of a named statistic, parsed from "name=min:max" with the name optional
+type Statistic struct {
+	Name string
+	Range
+}
+
+func (s *Statistic) Set(value string) error {
+
+	if name, rest, ok := strings.Cut(value, "="); ok {
+
+		name = strings.TrimSpace(name)
+
+		if _, ok := statistics[name]; !ok {
+			return fmt.Errorf("unknown statistic %q, expected one of %s", name, strings.Join(slices.Sorted(maps.Keys(statistics)), ", "))
+		}
+
+		s.Name, value = name, rest
+
+	}
+
+	return s.Range.Set(value)
+
+}
+
+func (s *Statistic) String() string {
+
+	if r := s.Range.String(); r != "" {
+		return s.Name + "=" + r
+	}
+
+	return ""
+
+}
+
+func (s *Statistic) Type() string {
+	return "statistic"
+}
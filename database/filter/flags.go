@@ -1,6 +1,11 @@
 package filter
 
 import (
+	"log/slog"
+	"path"
+	"slices"
+	"strings"
+
 	"github.com/spf13/pflag"
 	"github.com/yawn/instagpu/detect"
 )
@@ -14,24 +19,42 @@ type Flag interface {
 
 var Flags []Flag
 
+// region include and exclude flags, also used for selecting the regions to fetch
+var (
+	regionExclude *filterFlag[[]string]
+	regionInclude *filterFlag[[]string]
+)
+
+// zone include flag, also used for selecting the zones to choose the cheapest one from
+var zoneInclude *filterFlag[[]string]
+
 func init() {
 
-	gpuMemory := &filterFlag[uint64]{
-		description: "Filters by minimum GPU memory in GiB (no default)",
-		filter: func(memory uint64) Filter {
+	gpuMemory := &filterFlag[Range]{
+		description: "Filters by minimum GPU memory in GiB or a range like 16:48 (no default)",
+		filter: func(memory Range) Filter {
 			return func(p *detect.Prices) bool {
-				return p.Instance.GPU.Memory/1024 >= memory
+				return memory.Contains(float64(p.Instance.GPU.Memory / 1024))
 			}
 		},
-		install: func(flags *pflag.FlagSet) install[uint64] {
-			return flags.Uint64Var
+		install: bounded(false),
+		name:    "filter-instance-min-vram",
+	}
+
+	gpuNames := &filterFlag[[]string]{
+		description: "Filters by comma-separated GPU or accelerator names, e.g. \"A10G,L4\" (no default)",
+		filter: func(names []string) Filter {
+			return func(p *detect.Prices) bool {
+				return contains(names, p.Instance.GPU.Name)
+			}
 		},
-		name: "filter-instance-min-vram",
+		install: slice,
+		name:    "filter-gpu-names",
 	}
 
-	gpuTFLOPS := &filterFlag[float64]{
-		description: "Filters by minimum GPU TFLOPs (no default)",
-		filter: func(tflops float64) Filter {
+	gpuTFLOPS := &filterFlag[Range]{
+		description: "Filters by minimum GPU TFLOPs or a range like 10:50 (no default)",
+		filter: func(tflops Range) Filter {
 			return func(p *detect.Prices) bool {
 
 				perf := p.Instance.GPU.FP32
@@ -40,75 +63,299 @@ func init() {
 					return false
 				}
 
-				return *perf >= tflops
+				return tflops.Contains(*perf)
 
 			}
 		},
-		install: func(flags *pflag.FlagSet) install[float64] {
-			return flags.Float64Var
+		install: bounded(false),
+		name:    "filter-gpu-min-tflops",
+	}
+
+	gpuVendor := &filterFlag[[]string]{
+		description: "Filters by comma-separated GPU or accelerator vendor names, e.g. NVIDIA, AMD, AWS or Habana (no default)",
+		filter: func(vendors []string) Filter {
+			return func(p *detect.Prices) bool {
+				return contains(vendors, p.Instance.GPU.Vendor)
+			}
 		},
-		name: "filter-gpu-min-tflops",
+		install: slice,
+		name:    "filter-gpu-vendor",
 	}
 
-	gpuVendor := &filterFlag[string]{
-		description: "Filters by GPU vendor name (no default)",
-		filter: func(vendor string) Filter {
+	instanceArch := &filterFlag[[]string]{
+		description: "Filters by comma-separated instance architectures, x86_64 or arm64 (no default)",
+		filter: func(archs []string) Filter {
 			return func(p *detect.Prices) bool {
-				return p.Instance.GPU.Vendor == vendor
+				return contains(archs, p.Instance.Arch)
 			}
 		},
-		install: func(flags *pflag.FlagSet) install[string] {
-			return flags.StringVar
+		install: slice,
+		name:    "filter-instance-arch",
+	}
+
+	instanceExclude := &filterFlag[[]string]{
+		description: "Filters out instances with names matching comma-separated globs, e.g. \"p5*,*.metal\" (no default)",
+		filter: func(values []string) Filter {
+
+			globs := patterns(values)
+
+			return func(p *detect.Prices) bool {
+				return !match(globs, p.Instance.Name)
+			}
+
 		},
-		name: "filter-gpu-vendor",
+		install: slice,
+		name:    "filter-instance-exclude",
 	}
 
-	instanceMemory := &filterFlag[uint64]{
-		description: "Filters by minimum compute memory in GiB (no default)",
-		filter: func(memory uint64) Filter {
+	instanceInclude := &filterFlag[[]string]{
+		description: "Filters by instance names matching comma-separated globs, e.g. \"g5.*,g6.*\" (no default)",
+		filter: func(values []string) Filter {
+
+			globs := patterns(values)
+
 			return func(p *detect.Prices) bool {
-				return p.Instance.Memory >= memory*1024
+				return match(globs, p.Instance.Name)
 			}
+
 		},
-		install: func(flags *pflag.FlagSet) install[uint64] {
-			return flags.Uint64Var
+		install: slice,
+		name:    "filter-instance-include",
+	}
+
+	instanceMemory := &filterFlag[Range]{
+		description: "Filters by minimum compute memory in GiB or a range like 32:128 (no default)",
+		filter: func(memory Range) Filter {
+			return func(p *detect.Prices) bool {
+				return memory.Contains(float64(p.Instance.Memory / 1024))
+			}
 		},
-		name: "filter-instance-min-ram",
+		install: bounded(false),
+		name:    "filter-instance-min-ram",
 	}
 
-	instancePrice := &filterFlag[float64]{
-		description: "Filters by maximum average instance price in USD / h (no default)",
-		filter: func(price float64) Filter {
+	instancePrice := &filterFlag[Range]{
+		description: "Filters by maximum average instance price in USD / h or a range like 0.5:2 (no default)",
+		filter: func(price Range) Filter {
 			return func(p *detect.Prices) bool {
-				return p.Avg <= price
+				return price.Contains(p.Avg)
 			}
 		},
-		install: func(flags *pflag.FlagSet) install[float64] {
-			return flags.Float64Var
+		install: bounded(true),
+		name:    "filter-instance-max-price",
+	}
+
+	instanceZones := &filterFlag[Range]{
+		description: "Filters by minimum number of availability zones offering the instance as spot or a range like 2:4 (no default)",
+		filter: func(azs Range) Filter {
+			return func(p *detect.Prices) bool {
+				return azs.Contains(float64(p.AvailabilityZones))
+			}
+		},
+		install: bounded(false),
+		name:    "filter-instance-min-azs",
+	}
+
+	regionExclude = &filterFlag[[]string]{
+		description: "Filters out regions matching comma-separated globs, which are not fetched either, e.g. \"ap-*\" (no default)",
+		filter: func(values []string) Filter {
+
+			globs := patterns(values)
+
+			return func(p *detect.Prices) bool {
+				return !match(globs, p.Instance.Region.Name)
+			}
+
+		},
+		install: slice,
+		name:    "filter-region-exclude",
+	}
+
+	regionInclude = &filterFlag[[]string]{
+		description: "Filters by regions matching comma-separated globs, only fetching those, e.g. \"eu-*\" (no default)",
+		filter: func(values []string) Filter {
+
+			globs := patterns(values)
+
+			return func(p *detect.Prices) bool {
+				return match(globs, p.Instance.Region.Name)
+			}
+
 		},
-		name: "filter-instance-max-price",
+		install: slice,
+		name:    "filter-region-include",
 	}
 
-	regionLatency := &filterFlag[uint64]{
-		description: "Filters by maximum region latency (no default)",
-		filter: func(latency uint64) Filter {
+	regionLatency := &filterFlag[Statistic]{
+		description: "Filters by maximum average region latency in ms or a range like 10:40, optionally of another statistic like p95=40, stddev=5 or loss=10 in percent, never matching regions of unmeasured latency (no default)",
+		filter: func(latency Statistic) Filter {
+
+			stat := statistics[latency.Name]
+
 			return func(p *detect.Prices) bool {
-				return p.Instance.Region.Latency.Avg <= latency
+
+				l := &p.Instance.Region.Latency
+
+				return l.Measured() && latency.Contains(stat(l)) // unmeasured fails any bound
+
 			}
+
 		},
-		install: func(flags *pflag.FlagSet) install[uint64] {
-			return flags.Uint64Var
+		install: statistic(true),
+		name:    "filter-region-max-latency",
+	}
+
+	zoneInclude = &filterFlag[[]string]{
+		description: "Filters by instances offered in availability zones with ids or names matching comma-separated globs, e.g. \"euw1-az1,us-east-1?\", also limiting the cheapest zone to these (no default)",
+		filter: func(values []string) Filter {
+
+			zones := MatchZones(values)
+
+			return func(p *detect.Prices) bool {
+				return slices.ContainsFunc(p.Zones, zones)
+			}
+
 		},
-		name: "filter-region-max-latency",
+		install: slice,
+		name:    "filter-zone-include",
 	}
 
 	Flags = []Flag{
 		gpuMemory,
+		gpuNames,
 		gpuTFLOPS,
 		gpuVendor,
+		instanceArch,
+		instanceExclude,
+		instanceInclude,
 		instanceMemory,
 		instancePrice,
+		instanceZones,
+		regionExclude,
+		regionInclude,
 		regionLatency,
+		zoneInclude,
+	}
+
+}
+
+// statistics are the latency statistics targetable by the latency filter
+var statistics = map[string]func(l *detect.Latency) float64{
+	"avg":    func(l *detect.Latency) float64 { return l.Avg },
+	"loss":   func(l *detect.Latency) float64 { return l.Loss },
+	"max":    func(l *detect.Latency) float64 { return l.Max },
+	"min":    func(l *detect.Latency) float64 { return l.Min },
+	"p50":    func(l *detect.Latency) float64 { return l.P50 },
+	"p95":    func(l *detect.Latency) float64 { return l.P95 },
+	"stddev": func(l *detect.Latency) float64 { return l.StdDev },
+}
+
+// Regions returns a selector of region names from the region include and exclude flags, for skipping
+// regions while fetching - it is nil if neither is set
+func Regions() func(name string) bool {
+
+	var filters []func(name string) bool
+
+	for _, flag := range []*filterFlag[[]string]{regionExclude, regionInclude} {
+
+		if !flag.IsSet() {
+			continue
+		}
+
+		filter := flag.Apply()
+
+		filters = append(filters, func(name string) bool {
+			return filter(&detect.Prices{
+				Instance: &detect.Instance{
+					Region: &detect.Region{Name: name},
+				},
+			})
+		})
+
+	}
+
+	if len(filters) == 0 {
+		return nil
+	}
+
+	return func(name string) bool {
+
+		for _, filter := range filters {
+
+			if !filter(name) {
+				return false
+			}
+
+		}
+
+		return true
+
+	}
+
+}
+
+// Zones returns a selector of availability zones from the zone include flag, for choosing the cheapest zone
+// among the selected ones only - it is nil if the flag is not set
+func Zones() func(zone *detect.Zone) bool {
+
+	if !zoneInclude.IsSet() {
+		return nil
 	}
 
+	return MatchZones(zoneInclude.value)
+
+}
+
+// MatchZones returns a selector of availability zones with ids or names matching comma-separated globs
+func MatchZones(values []string) func(zone *detect.Zone) bool {
+
+	globs := patterns(values)
+
+	return func(zone *detect.Zone) bool {
+		return match(globs, zone.ID) || match(globs, zone.Name)
+	}
+
+}
+
+// contains reports if value equals any of values, ignoring case
+func contains(values []string, value string) bool {
+	return slices.ContainsFunc(values, func(v string) bool {
+		return strings.EqualFold(strings.TrimSpace(v), value)
+	})
+}
+
+// match reports if name matches any of the globs, see path.Match
+func match(globs []string, name string) bool {
+
+	for _, glob := range globs {
+
+		if ok, _ := path.Match(glob, name); ok {
+			return true
+		}
+
+	}
+
+	return false
+
+}
+
+// patterns trims globs, reporting malformed ones which never match
+func patterns(globs []string) []string {
+
+	var patterns []string
+
+	for _, glob := range globs {
+
+		glob = strings.TrimSpace(glob)
+
+		if _, err := path.Match(glob, ""); err != nil {
+			slog.Warn("malformed glob never matches", slog.String("glob", glob))
+		}
+
+		patterns = append(patterns, glob)
+
+	}
+
+	return patterns
+
 }
@@ -0,0 +1,324 @@
+package filter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yawn/instagpu/detect"
+)
+
+// parse installs all flags into a fresh flag set, parses args and returns the active filters
+func parse(t *testing.T, args ...string) []Filter {
+
+	flags := pflag.NewFlagSet(t.Name(), pflag.ContinueOnError)
+
+	for _, flag := range Flags {
+		flag.Install(flags)
+	}
+
+	require.NoError(t, flags.Parse(args))
+
+	var filters []Filter
+
+	for _, flag := range Flags {
+
+		if flag.IsSet() {
+			filters = append(filters, flag.Apply())
+		}
+
+	}
+
+	t.Cleanup(func() {
+
+		for _, flag := range Flags {
+			flag.Install(pflag.NewFlagSet("reset", pflag.ContinueOnError)) // resets to defaults
+		}
+
+	})
+
+	return filters
+
+}
+
+func TestFlagsGlobs(t *testing.T) {
+
+	prices := func(region, instance, gpu, arch string) *detect.Prices {
+		return &detect.Prices{
+			Instance: &detect.Instance{
+				Arch: arch,
+				GPU: &detect.GPU{
+					Name: gpu,
+				},
+				Name: instance,
+				Region: &detect.Region{
+					Name: region,
+				},
+			},
+		}
+	}
+
+	var (
+		g5  = prices("eu-west-1", "g5.xlarge", "A10G", "x86_64")
+		g5g = prices("eu-central-1", "g5g.xlarge", "T4g", "arm64")
+		p5  = prices("us-east-1", "p5.48xlarge", "H100", "x86_64")
+	)
+
+	matching := func(filters []Filter) []*detect.Prices {
+
+		var matching []*detect.Prices
+
+	candidates:
+		for _, p := range []*detect.Prices{g5, g5g, p5} {
+
+			for _, filter := range filters {
+
+				if !filter(p) {
+					continue candidates
+				}
+
+			}
+
+			matching = append(matching, p)
+
+		}
+
+		return matching
+
+	}
+
+	for _, tt := range []struct {
+		args     []string
+		expected []*detect.Prices
+	}{
+		{[]string{"--filter-region-include", "eu-*"}, []*detect.Prices{g5, g5g}},
+		{[]string{"--filter-region-include", "eu-*, us-east-1", "--filter-region-exclude", "eu-central-*"}, []*detect.Prices{g5, p5}},
+		{[]string{"--filter-instance-include", "g5.*,p5.*"}, []*detect.Prices{g5, p5}},
+		{[]string{"--filter-instance-exclude", "p5*"}, []*detect.Prices{g5, g5g}},
+		{[]string{"--filter-gpu-names", "a10g,H100"}, []*detect.Prices{g5, p5}},
+		{[]string{"--filter-instance-arch", "arm64"}, []*detect.Prices{g5g}},
+	} {
+
+		t.Run(tt.args[0], func(t *testing.T) {
+			assert.Equal(t, tt.expected, matching(parse(t, tt.args...)))
+		})
+
+	}
+
+}
+
+func TestFlagsRegions(t *testing.T) {
+
+	assert := assert.New(t)
+
+	parse(t)
+
+	assert.Nil(Regions(), "nothing to push down")
+
+	parse(t, "--filter-region-include", "eu-*", "--filter-region-exclude", "eu-south-*")
+
+	regions := Regions()
+
+	if assert.NotNil(regions) {
+		assert.True(regions("eu-west-1"))
+		assert.False(regions("eu-south-2"))
+		assert.False(regions("us-east-1"))
+	}
+
+}
+
+func TestFlagsRanges(t *testing.T) {
+
+	var (
+		cheap = &detect.Prices{
+			Avg: 0,
+			Instance: &detect.Instance{
+				GPU:    &detect.GPU{Memory: 16 * 1024, Vendor: "AMD"},
+				Region: &detect.Region{},
+			},
+		}
+		large = &detect.Prices{
+			Avg: 4,
+			Instance: &detect.Instance{
+				GPU:    &detect.GPU{Memory: 80 * 1024, Vendor: "NVIDIA"},
+				Region: &detect.Region{},
+			},
+		}
+	)
+
+	for _, tt := range []struct {
+		args  []string
+		cheap bool
+		large bool
+	}{
+		{[]string{"--filter-instance-max-price", "0"}, true, false},
+		{[]string{"--filter-instance-max-price", "1:"}, false, true},
+		{[]string{"--filter-instance-max-price", "0.5:5"}, false, true},
+		{[]string{"--filter-instance-min-vram", "24"}, false, true},
+		{[]string{"--filter-instance-min-vram", ":24"}, true, false},
+		{[]string{"--filter-instance-min-vram", "16:80"}, true, true},
+		{[]string{"--filter-gpu-vendor", "nvidia,amd"}, true, true},
+		{[]string{"--filter-gpu-vendor", "AMD", "--filter-gpu-vendor", "Habana"}, true, false},
+	} {
+
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+
+			assert := assert.New(t)
+
+			filters := parse(t, tt.args...)
+
+			require.Len(t, filters, 1)
+
+			assert.Equal(tt.cheap, filters[0](cheap))
+			assert.Equal(tt.large, filters[0](large))
+
+		})
+
+	}
+
+}
+
+func TestRange(t *testing.T) {
+
+	for _, tt := range []struct {
+		value    string
+		upper    bool
+		expected string
+		err      string
+	}{
+		{value: "5", expected: "5:"},
+		{value: "5", upper: true, expected: ":5"},
+		{value: "0.5:2", expected: "0.5:2"},
+		{value: " :2", expected: ":2"},
+		{value: ":", err: "lacks both bounds"},
+		{value: "2:1", err: "lower bound above its upper bound"},
+		{value: "a:1", err: `invalid number "a"`},
+	} {
+
+		t.Run(tt.value, func(t *testing.T) {
+
+			assert := assert.New(t)
+
+			r := &Range{upper: tt.upper}
+
+			err := r.Set(tt.value)
+
+			if tt.err != "" {
+				assert.ErrorContains(err, tt.err)
+				return
+			}
+
+			assert.NoError(err)
+			assert.Equal(tt.expected, r.String())
+
+		})
+
+	}
+
+}
+
+func TestFlagsLatency(t *testing.T) {
+
+	prices := &detect.Prices{
+		Instance: &detect.Instance{
+			Region: &detect.Region{
+				Latency: detect.Latency{
+					Avg:    20,
+					Loss:   10,
+					P95:    60,
+					StdDev: 15,
+				},
+			},
+		},
+	}
+
+	unmeasured := &detect.Prices{
+		Instance: &detect.Instance{
+			Region: &detect.Region{},
+		},
+	}
+
+	for _, tt := range []struct {
+		value string
+		match bool
+	}{
+		{"30", true},
+		{"10", false},
+		{"p95=50", false},
+		{"p95=50:70", true},
+		{"stddev=20", true},
+		{"loss=5", false},
+		{"loss=:10", true},
+	} {
+
+		t.Run(tt.value, func(t *testing.T) {
+
+			filters := parse(t, "--filter-region-max-latency", tt.value)
+
+			require.Len(t, filters, 1)
+			assert.Equal(t, tt.match, filters[0](prices))
+			assert.False(t, filters[0](unmeasured), "unmeasured fails any bound")
+
+		})
+
+	}
+
+	flags := pflag.NewFlagSet(t.Name(), pflag.ContinueOnError)
+
+	for _, flag := range Flags {
+		flag.Install(flags)
+	}
+
+	assert.ErrorContains(t, flags.Parse([]string{"--filter-region-max-latency", "p99=40"}), `unknown statistic "p99"`)
+
+}
+
+func TestFlagsZones(t *testing.T) {
+
+	prices := &detect.Prices{
+		AvailabilityZones: 2,
+		Instance:          &detect.Instance{},
+		Zones: []*detect.Zone{
+			{ID: "euw1-az3", Name: "eu-west-1a"},
+			{ID: "euw1-az1", Name: "eu-west-1b"},
+		},
+	}
+
+	for _, tt := range []struct {
+		args  []string
+		match bool
+	}{
+		{[]string{"--filter-instance-min-azs", "2"}, true},
+		{[]string{"--filter-instance-min-azs", "3"}, false},
+		{[]string{"--filter-instance-min-azs", ":1"}, false},
+		{[]string{"--filter-zone-include", "euw1-az1"}, true},
+		{[]string{"--filter-zone-include", "euw1-az2,eu-west-1?"}, true},
+		{[]string{"--filter-zone-include", "euw1-az2,eu-west-1c"}, false},
+	} {
+
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+
+			filters := parse(t, tt.args...)
+
+			require.Len(t, filters, 1)
+			assert.Equal(t, tt.match, filters[0](prices))
+
+		})
+
+	}
+
+	parse(t)
+
+	assert.Nil(t, Zones(), "all zones selected")
+
+	parse(t, "--filter-zone-include", "euw1-az3,eu-west-1c")
+
+	zones := Zones()
+
+	if assert.NotNil(t, zones) {
+		assert.Equal(t, []*detect.Zone{prices.Zones[0]}, prices.InZones(zones).Zones)
+	}
+
+}
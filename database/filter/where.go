@@ -0,0 +1,597 @@
+package filter
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/yawn/instagpu/detect"
+)
+
+// field is a named property of prices, either numeric or textual - numeric fields report false if the value
+// is unknown, e.g. for performance data missing from the catalog
+type field struct {
+	number func(p *detect.Prices) (float64, bool)
+	text   func(p *detect.Prices) string
+}
+
+// cheapest is a textual field of the cheapest availability zone, empty if zones are unknown
+func cheapest(fn func(z *detect.Zone) string) field {
+	return text(func(p *detect.Prices) string {
+
+		if zone := p.Cheapest(); zone != nil {
+			return fn(zone)
+		}
+
+		return ""
+
+	})
+}
+
+// latency is a numeric field of the region latency, unknown if unmeasured
+func latency[T float64 | uint](fn func(l *detect.Latency) T) field {
+	return field{
+		number: func(p *detect.Prices) (float64, bool) {
+
+			l := &p.Instance.Region.Latency
+
+			return float64(fn(l)), l.Measured()
+
+		},
+	}
+}
+
+func number[T float64 | uint | uint64](fn func(p *detect.Prices) T) field {
+	return field{
+		number: func(p *detect.Prices) (float64, bool) {
+			return float64(fn(p)), true
+		},
+	}
+}
+
+func perf(fn func(g *detect.GPU) *float64) field {
+	return field{
+		number: func(p *detect.Prices) (float64, bool) {
+
+			if value := fn(p.Instance.GPU); value != nil {
+				return *value, true
+			}
+
+			return 0, false
+
+		},
+	}
+}
+
+func text(fn func(p *detect.Prices) string) field {
+	return field{
+		text: fn,
+	}
+}
+
+// fields are all names usable in where expressions, memory in GiB like the filter flags
+var fields = map[string]field{
+	"gpu.bandwidth":          perf(func(g *detect.GPU) *float64 { return g.Bandwidth }),
+	"gpu.bf16":               perf(func(g *detect.GPU) *float64 { return g.BF16 }),
+	"gpu.count":              number(func(p *detect.Prices) uint { return p.Instance.GPU.Count }),
+	"gpu.fp16":               perf(func(g *detect.GPU) *float64 { return g.FP16 }),
+	"gpu.fp32":               perf(func(g *detect.GPU) *float64 { return g.FP32 }),
+	"gpu.fp8":                perf(func(g *detect.GPU) *float64 { return g.FP8 }),
+	"gpu.int8":               perf(func(g *detect.GPU) *float64 { return g.INT8 }),
+	"gpu.kind":               text(func(p *detect.Prices) string { return p.Instance.GPU.Kind }),
+	"gpu.memory":             number(func(p *detect.Prices) uint64 { return p.Instance.GPU.Memory / 1024 }),
+	"gpu.name":               text(func(p *detect.Prices) string { return p.Instance.GPU.Name }),
+	"gpu.vendor":             text(func(p *detect.Prices) string { return p.Instance.GPU.Vendor }),
+	"instance.arch":          text(func(p *detect.Prices) string { return p.Instance.Arch }),
+	"instance.clock":         number(func(p *detect.Prices) float64 { return p.Instance.ClockSpeed }),
+	"instance.cpus":          number(func(p *detect.Prices) uint { return p.Instance.Count }),
+	"instance.memory":        number(func(p *detect.Prices) uint64 { return p.Instance.Memory / 1024 }),
+	"instance.name":          text(func(p *detect.Prices) string { return p.Instance.Name }),
+	"instance.network":       number(func(p *detect.Prices) float64 { return p.Instance.Network }),
+	"instance.vendor":        text(func(p *detect.Prices) string { return p.Instance.Vendor }),
+	"price.avg":              number(func(p *detect.Prices) float64 { return p.Avg }),
+	"price.azs":              number(func(p *detect.Prices) uint { return p.AvailabilityZones }),
+	"price.current":          number(func(p *detect.Prices) float64 { return p.Current }),
+	"price.max":              number(func(p *detect.Prices) float64 { return p.Max }),
+	"price.median":           number(func(p *detect.Prices) float64 { return p.Median }),
+	"price.min":              number(func(p *detect.Prices) float64 { return p.Min }),
+	"price.p90":              number(func(p *detect.Prices) float64 { return p.P90 }),
+	"price.stddev":           number(func(p *detect.Prices) float64 { return p.StdDev }),
+	"price.trend":            number(func(p *detect.Prices) float64 { return p.Trend }),
+	"region.latency":         latency(func(l *detect.Latency) float64 { return l.Avg }),
+	"region.latency.avg":     latency(func(l *detect.Latency) float64 { return l.Avg }),
+	"region.latency.loss":    latency(func(l *detect.Latency) float64 { return l.Loss }),
+	"region.latency.max":     latency(func(l *detect.Latency) float64 { return l.Max }),
+	"region.latency.min":     latency(func(l *detect.Latency) float64 { return l.Min }),
+	"region.latency.p50":     latency(func(l *detect.Latency) float64 { return l.P50 }),
+	"region.latency.p95":     latency(func(l *detect.Latency) float64 { return l.P95 }),
+	"region.latency.samples": latency(func(l *detect.Latency) uint { return uint(l.Samples) }),
+	"region.latency.stddev":  latency(func(l *detect.Latency) float64 { return l.StdDev }),
+	"region.name":            text(func(p *detect.Prices) string { return p.Instance.Region.Name }),
+	"region.provider":        text(func(p *detect.Prices) string { return p.Instance.Region.Provider }),
+	"zone.id":                cheapest(func(z *detect.Zone) string { return z.ID }),
+	"zone.name":              cheapest(func(z *detect.Zone) string { return z.Name }),
+}
+
+// Fields returns the names of all fields usable in where expressions
+func Fields() []string {
+	return slices.Sorted(maps.Keys(fields))
+}
+
+// SyntaxError describes an invalid where expression, pointing at the offending token
+type SyntaxError struct {
+	Expression string
+	Message    string
+	Pos        int
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("invalid expression at position %d: %s\n\t%s\n\t%s^", e.Pos+1, e.Message, e.Expression, strings.Repeat(" ", e.Pos))
+}
+
+// token kinds
+const (
+	tokenEOF = iota
+	tokenIdent
+	tokenNumber
+	tokenOperator
+	tokenString
+)
+
+type token struct {
+	kind  int
+	pos   int
+	value string
+}
+
+func (t token) String() string {
+
+	switch t.kind {
+	case tokenEOF:
+		return "end of expression"
+	case tokenString:
+		return strconv.Quote(t.value)
+	}
+
+	return fmt.Sprintf("%q", t.value)
+
+}
+
+// operators, longest first
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")", ","}
+
+func lex(expression string) ([]token, error) {
+
+	var (
+		tokens []token
+		runes  = []rune(expression)
+	)
+
+	fail := func(pos int, format string, args ...any) error {
+		return &SyntaxError{
+			Expression: expression,
+			Message:    fmt.Sprintf(format, args...),
+			Pos:        pos,
+		}
+	}
+
+	isIdent := func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' || r == '_'
+	}
+
+	isNumber := func(r rune) bool {
+		return unicode.IsDigit(r) || r == '.'
+	}
+
+lexing:
+	for pos := 0; pos < len(runes); {
+
+		r := runes[pos]
+
+		switch {
+
+		case unicode.IsSpace(r):
+			pos++
+
+		case r == '"' || r == '\'':
+
+			end := pos + 1
+
+			for end < len(runes) && runes[end] != r {
+				end++
+			}
+
+			if end == len(runes) {
+				return nil, fail(pos, "unterminated string")
+			}
+
+			tokens = append(tokens, token{kind: tokenString, pos: pos, value: string(runes[pos+1 : end])})
+			pos = end + 1
+
+		case unicode.IsDigit(r) || (r == '-' && pos+1 < len(runes) && isNumber(runes[pos+1])):
+
+			end := pos + 1
+
+			for end < len(runes) && isNumber(runes[end]) {
+				end++
+			}
+
+			tokens = append(tokens, token{kind: tokenNumber, pos: pos, value: string(runes[pos:end])})
+			pos = end
+
+		case unicode.IsLetter(r):
+
+			end := pos + 1
+
+			for end < len(runes) && isIdent(runes[end]) {
+				end++
+			}
+
+			tokens = append(tokens, token{kind: tokenIdent, pos: pos, value: string(runes[pos:end])})
+			pos = end
+
+		default:
+
+			for _, operator := range operators {
+
+				if strings.HasPrefix(string(runes[pos:]), operator) {
+					tokens = append(tokens, token{kind: tokenOperator, pos: pos, value: operator})
+					pos += len([]rune(operator))
+					continue lexing
+				}
+
+			}
+
+			return nil, fail(pos, "unexpected character %q", r)
+
+		}
+
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: len(runes)}), nil
+
+}
+
+// parser compiles where expressions by recursive descent, following this grammar:
+//
+//	or         = and { "||" and }
+//	and        = unary { "&&" unary }
+//	unary      = "!" unary | "(" or ")" | comparison
+//	comparison = field ( "==" | "!=" | "<" | "<=" | ">" | ">=" ) value
+//	           | field [ "not" ] "in" "(" value { "," value } ")"
+type parser struct {
+	expression string
+	pos        int
+	tokens     []token
+}
+
+// Where compiles an expression like `gpu.vendor in ("NVIDIA") && (price.avg < 2 || region.latency < 40)`
+// into a filter, comparing text case-insensitively
+func Where(expression string) (Filter, error) {
+
+	tokens, err := lex(expression)
+
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{
+		expression: expression,
+		tokens:     tokens,
+	}
+
+	filter, err := p.or()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, p.fail(t, "unexpected %s, expected \"&&\" or \"||\"", t)
+	}
+
+	return filter, nil
+
+}
+
+func (p *parser) and() (Filter, error) {
+
+	left, err := p.unary()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept("&&") {
+
+		right, err := p.unary()
+
+		if err != nil {
+			return nil, err
+		}
+
+		left = func(left, right Filter) Filter {
+			return func(prices *detect.Prices) bool {
+				return left(prices) && right(prices)
+			}
+		}(left, right)
+
+	}
+
+	return left, nil
+
+}
+
+func (p *parser) accept(value string) bool {
+
+	if t := p.peek(); t.kind != tokenString && t.value == value {
+		p.pos++
+		return true
+	}
+
+	return false
+
+}
+
+func (p *parser) comparison() (Filter, error) {
+
+	t := p.next()
+
+	if t.kind != tokenIdent {
+		return nil, p.fail(t, "unexpected %s, expected a field", t)
+	}
+
+	f, ok := fields[t.value]
+
+	if !ok {
+		return nil, p.fail(t, "unknown field %q, expected one of %s", t.value, strings.Join(Fields(), ", "))
+	}
+
+	negate := p.accept("not")
+
+	if negate || p.accept("in") {
+
+		if negate && !p.accept("in") {
+			return nil, p.fail(p.peek(), "unexpected %s, expected \"in\"", p.peek())
+		}
+
+		filter, err := p.in(t, f)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if negate {
+			return not(filter), nil
+		}
+
+		return filter, nil
+
+	}
+
+	op := p.next()
+
+	if op.kind != tokenOperator || !slices.Contains([]string{"==", "!=", "<", "<=", ">", ">="}, op.value) {
+		return nil, p.fail(op, "unexpected %s, expected a comparison like \"==\" or \"in\"", op)
+	}
+
+	value, err := p.value(t, f)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if f.text != nil {
+
+		switch op.value {
+		case "==":
+			return func(prices *detect.Prices) bool {
+				return strings.EqualFold(f.text(prices), value.text)
+			}, nil
+		case "!=":
+			return func(prices *detect.Prices) bool {
+				return !strings.EqualFold(f.text(prices), value.text)
+			}, nil
+		}
+
+		return nil, p.fail(op, "operator %s not supported for text field %q", op, t.value)
+
+	}
+
+	compare := map[string]func(a, b float64) bool{
+		"==": func(a, b float64) bool { return a == b },
+		"!=": func(a, b float64) bool { return a != b },
+		"<":  func(a, b float64) bool { return a < b },
+		"<=": func(a, b float64) bool { return a <= b },
+		">":  func(a, b float64) bool { return a > b },
+		">=": func(a, b float64) bool { return a >= b },
+	}[op.value]
+
+	return func(prices *detect.Prices) bool {
+
+		actual, ok := f.number(prices)
+
+		return ok && compare(actual, value.number)
+
+	}, nil
+
+}
+
+func (p *parser) fail(t token, format string, args ...any) error {
+	return &SyntaxError{
+		Expression: p.expression,
+		Message:    fmt.Sprintf(format, args...),
+		Pos:        t.pos,
+	}
+}
+
+func (p *parser) in(name token, f field) (Filter, error) {
+
+	if t := p.next(); t.kind != tokenOperator || t.value != "(" {
+		return nil, p.fail(t, "unexpected %s, expected \"(\"", t)
+	}
+
+	var values []literal
+
+	for {
+
+		value, err := p.value(name, f)
+
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, value)
+
+		if p.accept(")") {
+			break
+		}
+
+		if t := p.next(); t.kind != tokenOperator || t.value != "," {
+			return nil, p.fail(t, "unexpected %s, expected \",\" or \")\"", t)
+		}
+
+	}
+
+	return func(prices *detect.Prices) bool {
+
+		if f.text != nil {
+
+			actual := f.text(prices)
+
+			return slices.ContainsFunc(values, func(value literal) bool {
+				return strings.EqualFold(actual, value.text)
+			})
+
+		}
+
+		actual, ok := f.number(prices)
+
+		return ok && slices.ContainsFunc(values, func(value literal) bool {
+			return actual == value.number
+		})
+
+	}, nil
+
+}
+
+func (p *parser) next() token {
+
+	t := p.peek()
+
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+
+	return t
+
+}
+
+func (p *parser) or() (Filter, error) {
+
+	left, err := p.and()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept("||") {
+
+		right, err := p.and()
+
+		if err != nil {
+			return nil, err
+		}
+
+		left = func(left, right Filter) Filter {
+			return func(prices *detect.Prices) bool {
+				return left(prices) || right(prices)
+			}
+		}(left, right)
+
+	}
+
+	return left, nil
+
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) unary() (Filter, error) {
+
+	if p.accept("!") {
+
+		filter, err := p.unary()
+
+		if err != nil {
+			return nil, err
+		}
+
+		return not(filter), nil
+
+	}
+
+	if open := p.peek(); p.accept("(") {
+
+		filter, err := p.or()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !p.accept(")") {
+			return nil, p.fail(p.peek(), "unexpected %s, expected \")\" to close %q at position %d", p.peek(), "(", open.pos+1)
+		}
+
+		return filter, nil
+
+	}
+
+	return p.comparison()
+
+}
+
+// literal is a constant compared against a field
+type literal struct {
+	number float64
+	text   string
+}
+
+func (p *parser) value(name token, f field) (literal, error) {
+
+	t := p.next()
+
+	switch {
+
+	case f.text != nil && (t.kind == tokenString || t.kind == tokenIdent):
+		return literal{text: t.value}, nil
+
+	case f.text != nil:
+		return literal{}, p.fail(t, "unexpected %s, expected text to compare with field %q", t, name.value)
+
+	case t.kind == tokenNumber:
+
+		number, err := strconv.ParseFloat(t.value, 64)
+
+		if err != nil {
+			return literal{}, p.fail(t, "invalid number %s", t)
+		}
+
+		return literal{number: number}, nil
+
+	}
+
+	return literal{}, p.fail(t, "unexpected %s, expected a number to compare with field %q", t, name.value)
+
+}
+
+func not(filter Filter) Filter {
+	return func(p *detect.Prices) bool {
+		return !filter(p)
+	}
+}
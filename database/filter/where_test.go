@@ -0,0 +1,123 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yawn/instagpu/detect"
+)
+
+func TestWhere(t *testing.T) {
+
+	var (
+		fp32 = 31.2
+		a10g = &detect.Prices{
+			AvailabilityZones: 3,
+			Avg:               1.5,
+			Instance: &detect.Instance{
+				Arch: "x86_64",
+				GPU: &detect.GPU{
+					Count:  1,
+					FP32:   &fp32,
+					Kind:   detect.KindGPU,
+					Memory: 24 * 1024,
+					Name:   "A10G",
+					Vendor: "NVIDIA",
+				},
+				Name: "g5.xlarge",
+				Region: &detect.Region{
+					Name:     "eu-west-1",
+					Provider: "aws",
+				},
+			},
+		}
+	)
+
+	a10g.Instance.Region.Latency = detect.Latency{Avg: 60, P95: 80}
+
+	for _, tt := range []struct {
+		expression string
+		match      bool
+	}{
+		{`gpu.vendor in ("NVIDIA") && gpu.memory >= 48 && (price.avg < 2 || region.latency < 40)`, false},
+		{`gpu.vendor in ("NVIDIA") && gpu.memory >= 24 && (price.avg < 2 || region.latency < 40)`, true},
+		{`gpu.vendor == 'nvidia'`, true},
+		{`gpu.vendor not in ("AMD", "Habana")`, true},
+		{`gpu.count in (1, 2)`, true},
+		{`!(price.azs > 2)`, false},
+		{`gpu.fp32 > 30 && gpu.bf16 > 0 || instance.name == g5.xlarge`, true},
+		{`gpu.bf16 > 0`, false},
+		{`!(gpu.bf16 > 0)`, true},
+		{`price.avg != 1.5 || region.provider != "aws"`, false},
+		{`price.avg >= -1`, true},
+		{`region.latency.p95 < 100 && region.latency.loss == 0`, true},
+	} {
+
+		t.Run(tt.expression, func(t *testing.T) {
+
+			filter, err := Where(tt.expression)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.match, filter(a10g))
+
+		})
+
+	}
+
+	unmeasured := *a10g.Instance
+	unmeasured.Region = &detect.Region{Name: "eu-west-1"}
+
+	for _, expression := range []string{`region.latency < 40`, `region.latency.loss == 0`, `region.latency.samples in (0)`} {
+
+		filter, err := Where(expression)
+
+		require.NoError(t, err)
+		assert.False(t, filter(&detect.Prices{Instance: &unmeasured}), "unmeasured latency is unknown: %s", expression)
+
+	}
+
+}
+
+func TestWhereErrors(t *testing.T) {
+
+	for _, tt := range []struct {
+		expression string
+		message    string
+		pos        int
+	}{
+		{`gpu.vram > 4`, `unknown field "gpu.vram"`, 0},
+		{`gpu.vendor == "NVIDIA" && price.avg <`, "unexpected end of expression, expected a number", 37},
+		{`gpu.vendor < "NVIDIA"`, `operator "<" not supported for text field "gpu.vendor"`, 11},
+		{`gpu.memory >= "48"`, `unexpected "48", expected a number to compare with field "gpu.memory"`, 14},
+		{`(price.avg < 2`, `expected ")" to close "(" at position 1`, 14},
+		{`price.avg < 2 price.min`, `unexpected "price.min", expected "&&" or "||"`, 14},
+		{`gpu.name == "A10G`, "unterminated string", 12},
+		{`price.avg ~ 2`, `unexpected character '~'`, 10},
+		{`gpu.vendor in ("AMD" "NVIDIA")`, `unexpected "NVIDIA", expected "," or ")"`, 21},
+		{`gpu.vendor not ("AMD")`, `unexpected "(", expected "in"`, 15},
+	} {
+
+		t.Run(tt.expression, func(t *testing.T) {
+
+			assert := assert.New(t)
+
+			_, err := Where(tt.expression)
+
+			var syntax *SyntaxError
+
+			if assert.ErrorAs(err, &syntax) {
+				assert.Contains(syntax.Message, tt.message)
+				assert.Equal(tt.pos, syntax.Pos)
+			}
+
+		})
+
+	}
+
+	_, err := Where(`gpu.vram > 4`)
+
+	assert.ErrorContains(t, err, "at position 1: unknown field \"gpu.vram\"")
+	assert.ErrorContains(t, err, "\tgpu.vram > 4\n\t^")
+
+}
@@ -0,0 +1,191 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/yawn/instagpu/provider"
+)
+
+const (
+	// CONCURRENCY is the default maximum of concurrent calls per provider
+	CONCURRENCY = 16
+
+	// REGION_CONCURRENCY is the default maximum of concurrent calls per provider region
+	REGION_CONCURRENCY = 4
+)
+
+const (
+	backoffAttempts = 8
+	backoffMax      = 10 * time.Second
+	backoffMin      = 100 * time.Millisecond
+)
+
+// limiter bounds concurrent calls against a provider and its regions, pacing all calls when the provider
+// starts throttling and relaxing again on success
+type limiter struct {
+	delay       time.Duration
+	mutex       sync.Mutex
+	provider    chan struct{}
+	regionLimit int
+	regions     map[string]chan struct{}
+}
+
+func newLimiter(concurrency, regionConcurrency int) *limiter {
+
+	if concurrency <= 0 {
+		concurrency = CONCURRENCY
+	}
+
+	if regionConcurrency <= 0 {
+		regionConcurrency = REGION_CONCURRENCY
+	}
+
+	return &limiter{
+		provider:    make(chan struct{}, concurrency),
+		regionLimit: regionConcurrency,
+		regions:     make(map[string]chan struct{}),
+	}
+
+}
+
+// do calls fn within the concurrency limits of region (or only the provider if region is empty), retrying
+// with adaptive backoff as long as fn is throttled
+func (l *limiter) do(ctx context.Context, region string, fn func() error) error {
+
+	for attempt := 1; ; attempt++ {
+
+		if err := sleep(ctx, l.pace()); err != nil {
+			return err
+		}
+
+		err := l.call(ctx, region, fn)
+
+		if !errors.Is(err, provider.ErrThrottled) {
+
+			if err == nil {
+				l.relax()
+			}
+
+			return err
+
+		}
+
+		if attempt == backoffAttempts {
+			return err
+		}
+
+		delay := l.throttle()
+
+		slog.Debug("throttled, backing off",
+			slog.Int("attempt", attempt),
+			slog.Duration("delay", delay),
+			slog.String("region", region),
+		)
+
+	}
+
+}
+
+// call acquires the region and provider slots and calls fn
+func (l *limiter) call(ctx context.Context, region string, fn func() error) error {
+
+	if region != "" {
+
+		slot := l.region(region)
+
+		select {
+		case slot <- struct{}{}:
+			defer func() { <-slot }()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+	}
+
+	select {
+	case l.provider <- struct{}{}:
+		defer func() { <-l.provider }()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	return fn()
+
+}
+
+// pace returns the current delay with jitter, to spread out calls while throttled
+func (l *limiter) pace() time.Duration {
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.delay == 0 {
+		return 0
+	}
+
+	return l.delay/2 + rand.N(l.delay/2+1)
+
+}
+
+func (l *limiter) region(name string) chan struct{} {
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	slot, ok := l.regions[name]
+
+	if !ok {
+		slot = make(chan struct{}, l.regionLimit)
+		l.regions[name] = slot
+	}
+
+	return slot
+
+}
+
+// relax halves the delay after a successful call
+func (l *limiter) relax() {
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.delay /= 2; l.delay < backoffMin {
+		l.delay = 0
+	}
+
+}
+
+// throttle doubles the delay after a throttled call
+func (l *limiter) throttle() time.Duration {
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.delay = min(max(l.delay*2, backoffMin), backoffMax)
+
+	return l.delay
+
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+
+	if d == 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+}
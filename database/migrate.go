@@ -0,0 +1,109 @@
+package database
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// migrations upgrade a raw database from the keyed version to the next one
+var migrations = map[uint]func(raw map[string]any){
+
+	// 1 lacks build, provider, region and window metadata - prices were always fetched for a week
+	1: func(raw map[string]any) {
+		raw["window"] = WINDOW
+	},
+
+	// 2 keys gpu fp32 performance as "fp32 " and region endpoints as "Endpoint"
+	2: func(raw map[string]any) {
+
+		prices, _ := raw["prices"].([]any)
+
+		for _, p := range prices {
+
+			instance, _ := dig(p, "instance").(map[string]any)
+
+			rename(dig(instance, "gpu"), "fp32 ", "fp32")
+			rename(dig(instance, "region"), "Endpoint", "endpoint")
+
+		}
+
+	},
+
+	// 3 averages prices unweighted and lacks further price statistics and zones - these cannot be rebuilt, so
+	// migrated databases are refetched, see Database.IsMigrated
+	3: func(raw map[string]any) {},
+}
+
+// dig returns the value of key if v is an object
+func dig(v any, key string) any {
+
+	if object, ok := v.(map[string]any); ok {
+		return object[key]
+	}
+
+	return nil
+
+}
+
+// rename moves the value of from to to if v is an object containing from
+func rename(v any, from, to string) {
+
+	object, ok := v.(map[string]any)
+
+	if !ok {
+		return
+	}
+
+	if value, ok := object[from]; ok {
+		delete(object, from)
+		object[to] = value
+	}
+
+}
+
+// migrate upgrades a raw database to VERSION, wrapping legacy bare lists of prices into a version 1 envelope -
+// it reports if any migration was applied
+func migrate(data []byte) ([]byte, bool, error) {
+
+	var raw map[string]any
+
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+
+		var prices []any
+
+		if err := json.Unmarshal(data, &prices); err != nil {
+			return nil, false, errors.Wrapf(err, "failed to decode legacy database")
+		}
+
+		raw = map[string]any{
+			"prices":  prices,
+			"version": float64(1),
+		}
+
+	} else if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, false, errors.Wrapf(err, "failed to decode database")
+	}
+
+	version, ok := raw["version"].(float64)
+
+	if !ok || version < 1 {
+		return nil, false, fmt.Errorf("missing database version")
+	}
+
+	if version > VERSION {
+		return nil, false, fmt.Errorf("database version %d is newer than supported version %d", uint(version), VERSION)
+	}
+
+	for v := uint(version); v < VERSION; v++ {
+		migrations[v](raw)
+		raw["version"] = v + 1
+	}
+
+	data, err := json.Marshal(raw)
+
+	return data, uint(version) < VERSION, err
+
+}
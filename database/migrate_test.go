@@ -0,0 +1,115 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMigrations(t *testing.T) {
+
+	write := func(t *testing.T, content string) string {
+
+		path := filepath.Join(t.TempDir(), "database.json")
+
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+		return path
+
+	}
+
+	t.Run("legacy", func(t *testing.T) {
+
+		assert := assert.New(t)
+
+		db, err := Load(write(t, `[{"avg": 1.5, "instance": {"name": "g5.xlarge"}}]`))
+
+		assert.NoError(err)
+		assert.EqualValues(VERSION, db.Version)
+		assert.Equal(WINDOW, db.Window)
+		assert.True(db.Fetched.IsZero())
+		assert.True(db.IsMigrated())
+		assert.Len(db.Prices, 1)
+		assert.Equal("g5.xlarge", db.Prices[0].Instance.Name)
+
+	})
+
+	t.Run("v1", func(t *testing.T) {
+
+		assert := assert.New(t)
+
+		db, err := Load(write(t, `{"version": 1, "fetched": "2024-09-24T10:00:00Z", "prices": []}`))
+
+		assert.NoError(err)
+		assert.EqualValues(VERSION, db.Version)
+		assert.Equal(WINDOW, db.Window)
+		assert.Equal(2024, db.Fetched.Year())
+
+	})
+
+	t.Run("v2", func(t *testing.T) {
+
+		assert := assert.New(t)
+
+		db, err := Load(write(t, `{"version": 2, "prices": [{"avg": 1.5, "availability_zones": 2, "instance": {
+			"name": "g5.xlarge",
+			"gpu": {"name": "A10G", "fp32 ": 31.2},
+			"region": {"Endpoint": "ec2.eu-west-1.amazonaws.com", "name": "eu-west-1"}
+		}}]}`))
+
+		assert.NoError(err)
+		assert.EqualValues(VERSION, db.Version)
+
+		if assert.Len(db.Prices, 1) {
+
+			instance := db.Prices[0].Instance
+
+			assert.EqualValues(2, db.Prices[0].AvailabilityZones)
+			assert.Equal(31.2, *instance.GPU.FP32)
+			assert.Equal("ec2.eu-west-1.amazonaws.com", instance.Region.Endpoint)
+
+		}
+
+	})
+
+	t.Run("v3", func(t *testing.T) {
+
+		assert := assert.New(t)
+
+		db, err := Load(write(t, `{"version": 3, "prices": [{"avg": 1.5, "instance": {"name": "g5.xlarge"}}]}`))
+
+		assert.NoError(err)
+		assert.EqualValues(VERSION, db.Version)
+		assert.True(db.IsMigrated(), "lacking price statistics and zones")
+
+		path := filepath.Join(t.TempDir(), "database.json")
+
+		require.NoError(t, db.Save(path))
+
+		db, err = Load(path)
+
+		assert.NoError(err)
+		assert.False(db.IsMigrated(), "current")
+
+	})
+
+	t.Run("newer", func(t *testing.T) {
+
+		_, err := Load(write(t, `{"version": 999, "prices": []}`))
+
+		assert.ErrorContains(t, err, "newer than supported")
+
+	})
+
+	t.Run("unversioned", func(t *testing.T) {
+
+		_, err := Load(write(t, `{"prices": []}`))
+
+		assert.ErrorContains(t, err, "missing database version")
+
+	})
+
+}
@@ -0,0 +1,325 @@
+// Package output renders ranked results in human and machine-readable formats
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"github.com/pkg/errors"
+	"github.com/yawn/instagpu/database"
+	"github.com/yawn/instagpu/detect"
+	"gopkg.in/yaml.v3"
+)
+
+// formats
+const (
+	CSV      = "csv"
+	JSON     = "json"
+	JSONL    = "jsonl"
+	TEMPLATE = "template"
+	TABLE    = "table"
+	TEXT     = "text"
+	YAML     = "yaml"
+)
+
+// COLUMNS are the default columns of table and csv output
+const COLUMNS = "index,provider,region,latency,instance,gpu_vendor,gpu_name,gpu_count,gpu_memory,price_avg,score,relative"
+
+// column renders a single value of a result
+type column func(r *database.Result) string
+
+func float(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func optional(v *float64) string {
+
+	if v == nil {
+		return ""
+	}
+
+	return float(*v)
+
+}
+
+func unsigned[T uint | uint64](v T) string {
+	return strconv.FormatUint(uint64(v), 10)
+}
+
+// latency renders a statistic of the region latency, if measured
+func latency(r *database.Result, value func(l *detect.Latency) float64) string {
+
+	if l := &r.Prices.Instance.Region.Latency; l.Measured() {
+		return float(value(l))
+	}
+
+	return ""
+
+}
+
+// zone renders a value of the cheapest availability zone, if known
+func zone(r *database.Result, value func(z *detect.Zone) string) string {
+
+	if z := r.Prices.Cheapest(); z != nil {
+		return value(z)
+	}
+
+	return ""
+
+}
+
+// columns are all columns by their stable names
+var columns = map[string]column{
+	"arch":          func(r *database.Result) string { return r.Prices.Instance.Arch },
+	"azs":           func(r *database.Result) string { return unsigned(r.Prices.AvailabilityZones) },
+	"clock":         func(r *database.Result) string { return float(r.Prices.Instance.ClockSpeed) },
+	"cpus":          func(r *database.Result) string { return unsigned(r.Prices.Instance.Count) },
+	"gpu_bandwidth": func(r *database.Result) string { return optional(r.Prices.Instance.GPU.Bandwidth) },
+	"gpu_bf16":      func(r *database.Result) string { return optional(r.Prices.Instance.GPU.BF16) },
+	"gpu_count":     func(r *database.Result) string { return unsigned(r.Prices.Instance.GPU.Count) },
+	"gpu_fp16":      func(r *database.Result) string { return optional(r.Prices.Instance.GPU.FP16) },
+	"gpu_fp32":      func(r *database.Result) string { return optional(r.Prices.Instance.GPU.FP32) },
+	"gpu_fp8":       func(r *database.Result) string { return optional(r.Prices.Instance.GPU.FP8) },
+	"gpu_int8":      func(r *database.Result) string { return optional(r.Prices.Instance.GPU.INT8) },
+	"gpu_kind":      func(r *database.Result) string { return r.Prices.Instance.GPU.Kind },
+	"gpu_memory":    func(r *database.Result) string { return unsigned(r.Prices.Instance.GPU.Memory) },
+	"gpu_name":      func(r *database.Result) string { return r.Prices.Instance.GPU.Name },
+	"gpu_vendor":    func(r *database.Result) string { return r.Prices.Instance.GPU.Vendor },
+	"index":         func(r *database.Result) string { return strconv.Itoa(r.Index) },
+	"index_max":     func(r *database.Result) string { return strconv.Itoa(r.IndexMax) },
+	"instance":      func(r *database.Result) string { return r.Prices.Instance.Name },
+	"latency":       func(r *database.Result) string { return latency(r, func(l *detect.Latency) float64 { return l.Avg }) },
+	"latency_loss":  func(r *database.Result) string { return latency(r, func(l *detect.Latency) float64 { return l.Loss }) },
+	"latency_p50":   func(r *database.Result) string { return latency(r, func(l *detect.Latency) float64 { return l.P50 }) },
+	"latency_p95":   func(r *database.Result) string { return latency(r, func(l *detect.Latency) float64 { return l.P95 }) },
+	"latency_stddev": func(r *database.Result) string {
+		return latency(r, func(l *detect.Latency) float64 { return l.StdDev })
+	},
+	"memory":        func(r *database.Result) string { return unsigned(r.Prices.Instance.Memory) },
+	"network":       func(r *database.Result) string { return float(r.Prices.Instance.Network) },
+	"price_avg":     func(r *database.Result) string { return float(r.Prices.Avg) },
+	"price_current": func(r *database.Result) string { return float(r.Prices.Current) },
+	"price_max":     func(r *database.Result) string { return float(r.Prices.Max) },
+	"price_median":  func(r *database.Result) string { return float(r.Prices.Median) },
+	"price_min":     func(r *database.Result) string { return float(r.Prices.Min) },
+	"price_p90":     func(r *database.Result) string { return float(r.Prices.P90) },
+	"price_stddev":  func(r *database.Result) string { return float(r.Prices.StdDev) },
+	"price_trend":   func(r *database.Result) string { return float(r.Prices.Trend) },
+	"provider":      func(r *database.Result) string { return r.Prices.Instance.Region.Provider },
+	"region":        func(r *database.Result) string { return r.Prices.Instance.Region.Name },
+	"relative":      func(r *database.Result) string { return float(r.Relative) },
+	"score":         func(r *database.Result) string { return float(r.Score) },
+	"scored":        func(r *database.Result) string { return strconv.FormatBool(r.Scored) },
+	"scorer":        func(r *database.Result) string { return r.Scorer },
+	"vendor":        func(r *database.Result) string { return r.Prices.Instance.Vendor },
+	"zone":          func(r *database.Result) string { return zone(r, func(z *detect.Zone) string { return z.Name }) },
+	"zone_id":       func(r *database.Result) string { return zone(r, func(z *detect.Zone) string { return z.ID }) },
+	"zone_price":    func(r *database.Result) string { return zone(r, func(z *detect.Zone) string { return float(z.Avg) }) },
+}
+
+// Columns returns the names of all columns
+func Columns() []string {
+	return slices.Sorted(maps.Keys(columns))
+}
+
+// Formats returns the names of all formats
+func Formats() []string {
+	return []string{TEXT, TABLE, CSV, JSON, JSONL, YAML, TEMPLATE}
+}
+
+// Options configure rendering
+type Options struct {
+	Columns  string // comma-separated column names for table and csv output, defaults to COLUMNS
+	Format   string // one of Formats, defaults to TEXT
+	Template string // text/template executed per result for template output
+}
+
+// Writer renders results in a format
+type Writer struct {
+	columns  []string
+	format   string
+	template *template.Template
+}
+
+// New validates options, returning a writer for them
+func New(options *Options) (*Writer, error) {
+
+	w := &Writer{
+		format: options.Format,
+	}
+
+	if w.format == "" {
+		w.format = TEXT
+	}
+
+	if !slices.Contains(Formats(), w.format) {
+		return nil, fmt.Errorf("unknown output format %q, expected one of %s", w.format, strings.Join(Formats(), ", "))
+	}
+
+	spec := options.Columns
+
+	if spec == "" {
+		spec = COLUMNS
+	}
+
+	for _, name := range strings.Split(spec, ",") {
+
+		name = strings.TrimSpace(name)
+
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("unknown column %q, expected one of %s", name, strings.Join(Columns(), ", "))
+		}
+
+		w.columns = append(w.columns, name)
+
+	}
+
+	if w.format == TEMPLATE {
+
+		if options.Template == "" {
+			return nil, fmt.Errorf("template output requires a template")
+		}
+
+		tmpl, err := template.New(TEMPLATE).Parse(options.Template)
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse template")
+		}
+
+		w.template = tmpl
+
+	}
+
+	return w, nil
+
+}
+
+// Write renders results to out
+func (w *Writer) Write(out io.Writer, results []*database.Result) error {
+
+	switch w.format {
+
+	case CSV:
+
+		enc := csv.NewWriter(out)
+
+		if err := enc.Write(w.columns); err != nil {
+			return errors.Wrapf(err, "failed to write csv")
+		}
+
+		for _, result := range results {
+
+			if err := enc.Write(w.row(result)); err != nil {
+				return errors.Wrapf(err, "failed to write csv")
+			}
+
+		}
+
+		enc.Flush()
+
+		return errors.Wrapf(enc.Error(), "failed to write csv")
+
+	case JSON:
+
+		if results == nil {
+			results = []*database.Result{} // an empty array rather than null
+		}
+
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "\t")
+
+		return errors.Wrapf(enc.Encode(results), "failed to write json")
+
+	case JSONL:
+
+		enc := json.NewEncoder(out)
+
+		for _, result := range results {
+
+			if err := enc.Encode(result); err != nil {
+				return errors.Wrapf(err, "failed to write json")
+			}
+
+		}
+
+		return nil
+
+	case TABLE:
+
+		tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+
+		fmt.Fprintln(tw, strings.ToUpper(strings.Join(w.columns, "\t")))
+
+		for _, result := range results {
+			fmt.Fprintln(tw, strings.Join(w.row(result), "\t"))
+		}
+
+		return errors.Wrapf(tw.Flush(), "failed to write table")
+
+	case TEMPLATE:
+
+		for _, result := range results {
+
+			if err := w.template.Execute(out, result); err != nil {
+				return errors.Wrapf(err, "failed to execute template")
+			}
+
+			fmt.Fprintln(out)
+
+		}
+
+		return nil
+
+	case YAML:
+
+		// yaml keys follow the json names, by way of a generic round-trip
+		raw, err := json.Marshal(results)
+
+		if err != nil {
+			return errors.Wrapf(err, "failed to write yaml")
+		}
+
+		var generic []any
+
+		if err := json.Unmarshal(raw, &generic); err != nil {
+			return errors.Wrapf(err, "failed to write yaml")
+		}
+
+		enc := yaml.NewEncoder(out)
+		enc.SetIndent(2)
+
+		if err := enc.Encode(generic); err != nil {
+			return errors.Wrapf(err, "failed to write yaml")
+		}
+
+		return errors.Wrapf(enc.Close(), "failed to write yaml")
+
+	}
+
+	for _, result := range results {
+		fmt.Fprintln(out, result)
+	}
+
+	return nil
+
+}
+
+func (w *Writer) row(result *database.Result) []string {
+
+	row := make([]string, len(w.columns))
+
+	for idx, name := range w.columns {
+		row[idx] = columns[name](result)
+	}
+
+	return row
+
+}
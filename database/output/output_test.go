@@ -0,0 +1,193 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yawn/instagpu/database"
+	"github.com/yawn/instagpu/detect"
+	"gopkg.in/yaml.v3"
+)
+
+func results() []*database.Result {
+
+	fp32 := 31.2
+
+	return []*database.Result{
+		{
+			Index:    0,
+			IndexMax: 1,
+			Prices: &detect.Prices{
+				AvailabilityZones: 3,
+				Avg:               0.45,
+				Instance: &detect.Instance{
+					Arch: "x86_64",
+					GPU: &detect.GPU{
+						Count:  1,
+						FP32:   &fp32,
+						Kind:   detect.KindGPU,
+						Memory: 24576,
+						Name:   "A10G",
+						Vendor: "NVIDIA",
+					},
+					Name: "g5.xlarge",
+					Region: &detect.Region{
+						Latency: detect.Latency{
+							Avg:     12.5,
+							Samples: 3,
+						},
+						Name:     "eu-west-1",
+						Provider: "aws",
+					},
+				},
+				Max: 0.5,
+				Min: 0.4,
+			},
+			Relative: 1,
+			Score:    69.33,
+			Scored:   true,
+			Scorer:   "fp32",
+		},
+		{
+			Index:    1,
+			IndexMax: 1,
+			Prices: &detect.Prices{
+				Avg: 1.2,
+				Instance: &detect.Instance{
+					GPU: &detect.GPU{
+						Name:   "Gaudi, HL-205",
+						Vendor: "Habana",
+					},
+					Name: "dl1.24xlarge",
+					Region: &detect.Region{
+						Name:     "us-east-1",
+						Provider: "aws",
+					},
+				},
+			},
+			Scorer: "fp32",
+		},
+	}
+
+}
+
+func render(t *testing.T, options *Options) string {
+
+	w, err := New(options)
+
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+
+	require.NoError(t, w.Write(&buf, results()))
+
+	return buf.String()
+
+}
+
+func TestFormats(t *testing.T) {
+
+	t.Run("csv", func(t *testing.T) {
+
+		assert.Equal(t, "instance,gpu_name,gpu_fp32,price_avg\n"+
+			"g5.xlarge,A10G,31.2,0.45\n"+
+			"dl1.24xlarge,\"Gaudi, HL-205\",,1.2\n",
+			render(t, &Options{Columns: "instance, gpu_name,gpu_fp32,price_avg", Format: CSV}))
+
+		assert.Equal(t, "instance,latency,latency_loss\n"+
+			"g5.xlarge,12.5,0\n"+
+			"dl1.24xlarge,,\n",
+			render(t, &Options{Columns: "instance,latency,latency_loss", Format: CSV}), "unmeasured latency is unknown")
+
+	})
+
+	t.Run("json", func(t *testing.T) {
+
+		var decoded []map[string]any
+
+		require.NoError(t, json.Unmarshal([]byte(render(t, &Options{Format: JSON})), &decoded))
+		require.Len(t, decoded, 2)
+
+		assert.Equal(t, "g5.xlarge", decoded[0]["prices"].(map[string]any)["instance"].(map[string]any)["name"])
+
+	})
+
+	t.Run("jsonl", func(t *testing.T) {
+
+		lines := bytes.Split(bytes.TrimSpace([]byte(render(t, &Options{Format: JSONL}))), []byte("\n"))
+
+		require.Len(t, lines, 2)
+
+		for _, line := range lines {
+			assert.True(t, json.Valid(line))
+		}
+
+	})
+
+	t.Run("table", func(t *testing.T) {
+
+		assert.Equal(t, "INDEX  INSTANCE      SCORE\n"+
+			"0      g5.xlarge     69.33\n"+
+			"1      dl1.24xlarge  0\n",
+			render(t, &Options{Columns: "index,instance,score", Format: TABLE}))
+
+	})
+
+	t.Run("template", func(t *testing.T) {
+
+		assert.Equal(t, "g5.xlarge@eu-west-1\ndl1.24xlarge@us-east-1\n",
+			render(t, &Options{Format: TEMPLATE, Template: "{{.Prices.Instance.Name}}@{{.Prices.Instance.Region.Name}}"}))
+
+	})
+
+	t.Run("yaml", func(t *testing.T) {
+
+		var decoded []map[string]any
+
+		require.NoError(t, yaml.Unmarshal([]byte(render(t, &Options{Format: YAML})), &decoded))
+		require.Len(t, decoded, 2)
+
+		assert.Equal(t, "fp32", decoded[0]["scorer"], "keys follow json names")
+		assert.Equal(t, "A10G", decoded[0]["prices"].(map[string]any)["instance"].(map[string]any)["gpu"].(map[string]any)["name"])
+
+	})
+
+	t.Run("text", func(t *testing.T) {
+		assert.Contains(t, render(t, &Options{}), "🏅")
+	})
+
+}
+
+func TestOptions(t *testing.T) {
+
+	assert := assert.New(t)
+
+	_, err := New(&Options{Format: "xml"})
+
+	assert.ErrorContains(err, `unknown output format "xml"`)
+
+	_, err = New(&Options{Columns: "instance,speed", Format: CSV})
+
+	assert.ErrorContains(err, `unknown column "speed"`)
+
+	_, err = New(&Options{Format: TEMPLATE})
+
+	assert.ErrorContains(err, "requires a template")
+
+	_, err = New(&Options{Format: TEMPLATE, Template: "{{.Prices"})
+
+	assert.ErrorContains(err, "failed to parse template")
+
+	w, err := New(&Options{Format: JSON})
+
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+
+	assert.NoError(w.Write(&buf, nil))
+	assert.Equal("[]\n", buf.String())
+
+}
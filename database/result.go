@@ -13,6 +13,8 @@ type Result struct {
 	Prices   *detect.Prices `json:"prices"`
 	Relative float64        `json:"score_relative_to_best"`
 	Score    float64        `json:"score"`
+	Scored   bool           `json:"scored"` // false if the scorer lacks performance data for the instance
+	Scorer   string         `json:"scorer"`
 }
 
 func (s *Result) String() string {
@@ -20,10 +22,21 @@ func (s *Result) String() string {
 	var b strings.Builder
 
 	fmt.Fprintf(&b, "🏅 %2d / %2d", s.Index, s.IndexMax)
-	fmt.Fprintf(&b, "\t🔢 %3.2f", s.Score)
-	fmt.Fprintf(&b, "\t🔝 %3.2f%%", s.Relative*100)
+
+	if s.Scored {
+		fmt.Fprintf(&b, "\t🔢 %3.2f %s/$", s.Score, s.Scorer)
+		fmt.Fprintf(&b, "\t🔝 %3.2f%%", s.Relative*100)
+	} else {
+		fmt.Fprintf(&b, "\t🔢 n/a %s/$", s.Scorer)
+		fmt.Fprintf(&b, "\t🔝 n/a")
+	}
+
 	fmt.Fprintf(&b, "\t%s", s.Prices.String())
 
+	if zone := s.Prices.Cheapest(); zone != nil {
+		fmt.Fprintf(&b, "\t🎯 %s %.2f USD/h", zone.Name, zone.Avg)
+	}
+
 	return b.String()
 
 }
@@ -0,0 +1,201 @@
+package database
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// descriptions document fields of the schema, keyed by type and json name
+var descriptions = map[string]string{
+	"GPU.bandwidth":                 "Memory bandwidth of all accelerators in GB/s",
+	"GPU.bf16":                      "BF16 performance of all accelerators in TFLOPS",
+	"GPU.count":                     "Number of accelerators",
+	"GPU.fp16":                      "FP16 performance of all accelerators in TFLOPS",
+	"GPU.fp32":                      "FP32 performance of all accelerators in TFLOPS",
+	"GPU.fp8":                       "FP8 performance of all accelerators in TFLOPS",
+	"GPU.int8":                      "INT8 performance of all accelerators in TOPS",
+	"GPU.kind":                      "Kind of accelerator: gpu, inference or neuron",
+	"GPU.memory":                    "Memory of all accelerators in MiB",
+	"Instance.clock_speed":          "Sustained clock speed of the processor in GHz",
+	"Instance.count":                "Number of processor cores",
+	"Instance.memory":               "Memory in MiB",
+	"Instance.network":              "Peak network bandwidth in Gbps",
+	"Latency.avg":                   "Average round-trip time in ms",
+	"Latency.loss":                  "Unanswered samples in percent",
+	"Latency.max":                   "Maximum round-trip time in ms",
+	"Latency.min":                   "Minimum round-trip time in ms",
+	"Latency.p50":                   "Median round-trip time in ms",
+	"Latency.p95":                   "95th percentile round-trip time in ms",
+	"Latency.samples":               "Number of samples sent",
+	"Latency.stddev":                "Standard deviation of round-trip times in ms, i.e. jitter",
+	"Prices.availability_zones":     "Number of availability zones offering the instance as spot",
+	"Prices.avg":                    "Average spot price in USD / h over the look-back window, weighted by how long each price held",
+	"Prices.current":                "Latest spot price in USD / h, averaged across availability zones",
+	"Prices.max":                    "Maximum spot price in USD / h over the look-back window",
+	"Prices.median":                 "Time-weighted median spot price in USD / h over the look-back window",
+	"Prices.min":                    "Minimum spot price in USD / h over the look-back window",
+	"Prices.p90":                    "Time-weighted 90th percentile spot price in USD / h over the look-back window",
+	"Prices.stddev":                 "Time-weighted standard deviation of spot prices in USD / h over the look-back window",
+	"Prices.trend":                  "Slope of a least squares fit of spot prices over the look-back window in USD / h per day, positive if rising",
+	"Prices.zones":                  "Price history and statistics per availability zone offering the instance as spot, sorted by name",
+	"Region.endpoint":               "Hostname of the provider API in the region, used for measuring latency",
+	"Region.latency":                "Round-trip latency statistics of the region endpoint, all zero if unmeasured",
+	"Region.provider":               "Name of the provider, e.g. aws",
+	"Result.index":                  "Rank of the result, starting at 0",
+	"Result.index_max":              "Highest rank of all results before filtering",
+	"Result.score":                  "Score per USD / h, higher is better",
+	"Result.score_relative_to_best": "Score relative to the best scored result",
+	"Result.scored":                 "False if the scorer lacks performance data for the instance",
+	"Result.scorer":                 "Name of the scorer, e.g. fp32 or a composite like bf16=0.7,vram=0.3",
+	"SpotPrice.availability_zone":   "Name of the availability zone",
+	"SpotPrice.price":               "Spot price in USD / h, holding until the next change",
+	"SpotPrice.time":                "Time the price took effect",
+	"Zone.avg":                      "Average spot price in USD / h in the zone over the look-back window, weighted by how long each price held",
+	"Zone.current":                  "Latest spot price in USD / h in the zone",
+	"Zone.history":                  "Spot price changes in the zone, oldest first, starting with the one in effect at the start of the look-back window",
+	"Zone.id":                       "Id of the zone, stable across accounts unlike its name, e.g. euw1-az1",
+	"Zone.max":                      "Maximum spot price in USD / h in the zone over the look-back window",
+	"Zone.median":                   "Time-weighted median spot price in USD / h in the zone over the look-back window",
+	"Zone.min":                      "Minimum spot price in USD / h in the zone over the look-back window",
+	"Zone.name":                     "Name of the zone, e.g. eu-west-1a",
+	"Zone.p90":                      "Time-weighted 90th percentile spot price in USD / h in the zone over the look-back window",
+	"Zone.stddev":                   "Time-weighted standard deviation of spot prices in USD / h in the zone over the look-back window",
+	"Zone.trend":                    "Slope of a least squares fit of spot prices in the zone in USD / h per day, positive if rising",
+}
+
+// Schema returns the JSON Schema of results, including nested prices, instances, accelerators and regions
+func Schema() ([]byte, error) {
+
+	defs := make(map[string]any)
+
+	root := schemaOf(reflect.TypeFor[Result](), defs)
+
+	schema := map[string]any{
+		"$defs":   defs,
+		"$id":     "https://github.com/yawn/instagpu/database/schema.json",
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"title":   "instagpu result",
+	}
+
+	for key, value := range root {
+		schema[key] = value
+	}
+
+	return json.MarshalIndent(schema, "", "\t")
+
+}
+
+// schemaOf returns the schema of t, collecting named structs in defs
+func schemaOf(t reflect.Type, defs map[string]any) map[string]any {
+
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case reflect.TypeFor[time.Duration]():
+		return map[string]any{"type": "integer", "description": "Duration in ns"}
+	case reflect.TypeFor[time.Time]():
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "minimum": 0}
+
+	case reflect.String:
+		return map[string]any{"type": "string"}
+
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": schemaOf(t.Elem(), defs)}
+
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaOf(t.Elem(), defs)}
+
+	case reflect.Struct:
+
+		if t.Name() == "" {
+			return object(t, "", defs)
+		}
+
+		ref := map[string]any{"$ref": "#/$defs/" + t.Name()}
+
+		if _, ok := defs[t.Name()]; !ok {
+			defs[t.Name()] = nil // guards against recursion
+			defs[t.Name()] = object(t, t.Name(), defs)
+		}
+
+		return ref
+
+	}
+
+	return map[string]any{}
+
+}
+
+// object returns the schema of struct t, documenting its properties under name
+func object(t reflect.Type, name string, defs map[string]any) map[string]any {
+
+	var (
+		properties = make(map[string]any)
+		required   = []string{}
+	)
+
+	for idx := range t.NumField() {
+
+		field := t.Field(idx)
+
+		if !field.IsExported() {
+			continue
+		}
+
+		key, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+
+		if key == "-" {
+			continue
+		}
+
+		if key == "" {
+			key = field.Name
+		}
+
+		property := schemaOf(field.Type, defs)
+
+		if description, ok := descriptions[name+"."+key]; ok {
+
+			if _, ok := property["$ref"]; ok {
+				property = map[string]any{"allOf": []any{property}}
+			}
+
+			property["description"] = description
+
+		}
+
+		properties[key] = property
+
+		if !strings.Contains(options, "omitempty") {
+			required = append(required, key)
+		}
+
+	}
+
+	return map[string]any{
+		"additionalProperties": false,
+		"properties":           properties,
+		"required":             required,
+		"type":                 "object",
+	}
+
+}
@@ -0,0 +1,114 @@
+package database
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yawn/instagpu/detect"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// golden compares actual with the golden file at path, rewriting it with -update
+func golden(t *testing.T, path string, actual []byte) {
+
+	if *update {
+		require.NoError(t, os.WriteFile(path, actual, 0644))
+	}
+
+	expected, err := os.ReadFile(path)
+
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), string(actual), "run go test ./database -update to regenerate %s", path)
+
+}
+
+func TestSchema(t *testing.T) {
+
+	schema, err := Schema()
+
+	require.NoError(t, err)
+
+	golden(t, "schema.json", append(schema, '\n'))
+
+}
+
+func TestSchemaResult(t *testing.T) {
+
+	fp32 := 31.2
+
+	result := &Result{
+		Index:    0,
+		IndexMax: 3,
+		Prices: &detect.Prices{
+			AvailabilityZones: 3,
+			Avg:               0.45,
+			Current:           0.42,
+			Instance: &detect.Instance{
+				Arch:       "x86_64",
+				ClockSpeed: 3.3,
+				Count:      2,
+				GPU: &detect.GPU{
+					Count:  1,
+					FP32:   &fp32,
+					Kind:   detect.KindGPU,
+					Memory: 24576,
+					Name:   "A10G",
+					Vendor: "NVIDIA",
+				},
+				Memory:  16384,
+				Name:    "g5.xlarge",
+				Network: 10,
+				Region: &detect.Region{
+					Endpoint: "ec2.eu-west-1.amazonaws.com",
+					Name:     "eu-west-1",
+					Provider: "aws",
+				},
+				Vendor: "AMD",
+			},
+			Max:    0.5,
+			Median: 0.44,
+			Min:    0.4,
+			P90:    0.49,
+			StdDev: 0.03,
+			Trend:  -0.01,
+			Zones: []*detect.Zone{
+				{
+					Avg:     0.43,
+					Current: 0.42,
+					History: []detect.SpotPrice{
+						{AvailabilityZone: "eu-west-1a", Price: 0.44, Time: time.Date(2024, 9, 22, 6, 0, 0, 0, time.UTC)},
+						{AvailabilityZone: "eu-west-1a", Price: 0.42, Time: time.Date(2024, 9, 23, 18, 0, 0, 0, time.UTC)},
+					},
+					ID:     "euw1-az3",
+					Max:    0.44,
+					Median: 0.44,
+					Min:    0.42,
+					Name:   "eu-west-1a",
+					P90:    0.44,
+					StdDev: 0.01,
+					Trend:  -0.02,
+				},
+			},
+		},
+		Relative: 1,
+		Score:    69.33,
+		Scored:   true,
+		Scorer:   "fp32",
+	}
+
+	result.Prices.Instance.Region.Latency.Avg = 20
+
+	actual, err := json.MarshalIndent(result, "", "\t")
+
+	require.NoError(t, err)
+
+	golden(t, filepath.Join("testdata", "result.json"), append(actual, '\n'))
+
+}
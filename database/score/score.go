@@ -0,0 +1,161 @@
+// Package score ranks prices by performance per dollar
+package score
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/yawn/instagpu/detect"
+)
+
+// DEFAULT is the name of the default scorer
+const DEFAULT = "fp32"
+
+type Scorer interface {
+	Name() string
+	Score(p *detect.Prices) (float64, bool) // false if the prices lack the required data
+}
+
+// perDollar scores a metric of an instance per USD / h
+type perDollar struct {
+	metric func(i *detect.Instance) *float64
+	name   string
+}
+
+func (s *perDollar) Name() string {
+	return s.name
+}
+
+func (s *perDollar) Score(p *detect.Prices) (float64, bool) {
+
+	value := s.metric(p.Instance)
+
+	if value == nil || p.Avg <= 0 {
+		return 0, false
+	}
+
+	return *value / p.Avg, true
+
+}
+
+// Scorers are the built-in scorers, by name
+var Scorers = map[string]Scorer{
+	"bandwidth": &perDollar{
+		metric: func(i *detect.Instance) *float64 { return i.GPU.Bandwidth },
+		name:   "bandwidth",
+	},
+	"bf16": &perDollar{
+		metric: func(i *detect.Instance) *float64 { return i.GPU.BF16 },
+		name:   "bf16",
+	},
+	"fp16": &perDollar{
+		metric: func(i *detect.Instance) *float64 { return i.GPU.FP16 },
+		name:   "fp16",
+	},
+	"fp32": &perDollar{ // equivalent to detect.Prices.PTGPIndex
+		metric: func(i *detect.Instance) *float64 { return i.GPU.FP32 },
+		name:   "fp32",
+	},
+	"fp8": &perDollar{
+		metric: func(i *detect.Instance) *float64 { return i.GPU.FP8 },
+		name:   "fp8",
+	},
+	"vram": &perDollar{
+		metric: func(i *detect.Instance) *float64 {
+			gib := float64(i.GPU.Memory) / 1024
+			return &gib
+		},
+		name: "vram",
+	},
+}
+
+// Composite combines scorers as weighted geometric mean, which keeps rankings independent of the units of
+// the individual scores
+type Composite struct {
+	Weights map[string]float64
+	scorers map[string]Scorer
+}
+
+func (c *Composite) Name() string {
+
+	var parts []string
+
+	for _, name := range slices.Sorted(maps.Keys(c.Weights)) {
+		parts = append(parts, fmt.Sprintf("%s=%g", name, c.Weights[name]))
+	}
+
+	return strings.Join(parts, ",")
+
+}
+
+func (c *Composite) Score(p *detect.Prices) (float64, bool) {
+
+	var (
+		log   float64
+		total float64
+	)
+
+	for name, weight := range c.Weights {
+
+		score, ok := c.scorers[name].Score(p)
+
+		if !ok || score <= 0 {
+			return 0, false
+		}
+
+		log += weight * math.Log(score)
+		total += weight
+
+	}
+
+	return math.Exp(log / total), true
+
+}
+
+// Parse returns a built-in scorer by name or a composite for a spec like "bf16=0.7,vram=0.3"
+func Parse(spec string) (Scorer, error) {
+
+	if scorer, ok := Scorers[spec]; ok {
+		return scorer, nil
+	}
+
+	if !strings.Contains(spec, "=") {
+		return nil, fmt.Errorf("unknown scorer %q, use one of %s or a weighted composite like \"bf16=0.7,vram=0.3\"",
+			spec, strings.Join(slices.Sorted(maps.Keys(Scorers)), ", "))
+	}
+
+	composite := &Composite{
+		Weights: make(map[string]float64),
+		scorers: make(map[string]Scorer),
+	}
+
+	for _, part := range strings.Split(spec, ",") {
+
+		name, value, _ := strings.Cut(part, "=")
+
+		name = strings.TrimSpace(name)
+
+		scorer, ok := Scorers[name]
+
+		if !ok {
+			return nil, fmt.Errorf("unknown scorer %q in composite %q", name, spec)
+		}
+
+		weight, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+
+		if err != nil || weight <= 0 {
+			return nil, fmt.Errorf("invalid weight %q for scorer %q, must be a positive number", value, name)
+		}
+
+		composite.Weights[name] = weight
+		composite.scorers[name] = scorer
+
+	}
+
+	return composite, nil
+
+}
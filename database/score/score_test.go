@@ -0,0 +1,93 @@
+package score
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yawn/instagpu/detect"
+)
+
+func prices(avg float64, memory uint64, bf16 *float64) *detect.Prices {
+	return &detect.Prices{
+		Avg: avg,
+		Instance: &detect.Instance{
+			GPU: &detect.GPU{
+				BF16:   bf16,
+				Memory: memory * 1024,
+			},
+		},
+	}
+}
+
+func TestScorers(t *testing.T) {
+
+	assert := assert.New(t)
+
+	bf16 := 100.0
+
+	score, ok := Scorers["bf16"].Score(prices(2, 24, &bf16))
+
+	assert.True(ok)
+	assert.Equal(50.0, score)
+
+	score, ok = Scorers["vram"].Score(prices(2, 24, nil))
+
+	assert.True(ok)
+	assert.Equal(12.0, score)
+
+	_, ok = Scorers["bf16"].Score(prices(2, 24, nil))
+
+	assert.False(ok, "missing performance data")
+
+	_, ok = Scorers["vram"].Score(prices(0, 24, nil))
+
+	assert.False(ok, "missing price")
+
+}
+
+func TestComposite(t *testing.T) {
+
+	assert := assert.New(t)
+
+	scorer, err := Parse("bf16=1, vram=1")
+
+	require.NoError(t, err)
+
+	assert.Equal("bf16=1,vram=1", scorer.Name())
+
+	bf16 := 100.0
+
+	score, ok := scorer.Score(prices(1, 25, &bf16))
+
+	assert.True(ok)
+	assert.InDelta(50, score, 1e-9, "geometric mean of 100 and 25")
+
+	_, ok = scorer.Score(prices(1, 25, nil))
+
+	assert.False(ok, "any component missing")
+
+}
+
+func TestParse(t *testing.T) {
+
+	assert := assert.New(t)
+
+	scorer, err := Parse(DEFAULT)
+
+	assert.NoError(err)
+	assert.Equal("fp32", scorer.Name())
+
+	_, err = Parse("fp64")
+
+	assert.ErrorContains(err, `unknown scorer "fp64"`)
+
+	_, err = Parse("bf16=0.5,fp64=0.5")
+
+	assert.ErrorContains(err, `unknown scorer "fp64" in composite`)
+
+	_, err = Parse("bf16=-1")
+
+	assert.ErrorContains(err, "must be a positive number")
+
+}
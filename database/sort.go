@@ -0,0 +1,168 @@
+package database
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+)
+
+// SORT is the default sort order
+const SORT = "score"
+
+// Key is a sort key, ranking best-first unless reversed
+type Key struct {
+	Name     string
+	Reversed bool
+}
+
+// Order is a sequence of sort keys, later keys breaking ties of earlier ones
+type Order []Key
+
+// keys compare results best-first
+var keys = map[string]func(a, b *Result) int{
+	"azs": func(a, b *Result) int {
+		return cmp.Compare(b.Prices.AvailabilityZones, a.Prices.AvailabilityZones)
+	},
+	"latency": func(a, b *Result) int {
+		return cmp.Compare(latency(a), latency(b))
+	},
+	"price": func(a, b *Result) int {
+		return cmp.Compare(a.Prices.Avg, b.Prices.Avg)
+	},
+	"score": func(a, b *Result) int {
+		return cmp.Compare(b.Score, a.Score)
+	},
+	"trend": func(a, b *Result) int {
+		return cmp.Compare(a.Prices.Trend, b.Prices.Trend)
+	},
+	"vram": func(a, b *Result) int {
+		return cmp.Compare(b.Prices.Instance.GPU.Memory, a.Prices.Instance.GPU.Memory)
+	},
+}
+
+// missing reports if a result lacks the value of a sort key, ranking it last regardless of direction
+var missing = map[string]func(r *Result) bool{
+	"latency": func(r *Result) bool {
+		return !r.Prices.Instance.Region.Latency.Measured()
+	},
+}
+
+// Keys returns the names of all sort keys
+func Keys() []string {
+	return slices.Sorted(maps.Keys(keys))
+}
+
+// ParseOrder parses a comma-separated list of sort keys, each optionally prefixed with "-" to reverse it
+func ParseOrder(spec string) (Order, error) {
+
+	var order Order
+
+	for _, name := range strings.Split(spec, ",") {
+
+		name = strings.TrimSpace(name)
+
+		if name == "" {
+			continue
+		}
+
+		key := Key{
+			Name:     strings.TrimPrefix(name, "-"),
+			Reversed: strings.HasPrefix(name, "-"),
+		}
+
+		if _, ok := keys[key.Name]; !ok {
+			return nil, fmt.Errorf("unknown sort key %q, expected one of %s", key.Name, strings.Join(Keys(), ", "))
+		}
+
+		order = append(order, key)
+
+	}
+
+	return order, nil
+
+}
+
+// compare orders scored results before unscored ones, then by the keys of the order (results missing the
+// value of a key last in either direction) and finally by provider, region and instance name for
+// deterministic ranks
+func (o Order) compare(a, b *Result) int {
+
+	if a.Scored != b.Scored {
+
+		if a.Scored {
+			return -1
+		}
+
+		return 1
+
+	}
+
+	for _, key := range o {
+
+		if lacks, ok := missing[key.Name]; ok {
+
+			if ma, mb := lacks(a), lacks(b); ma != mb {
+
+				if ma {
+					return 1
+				}
+
+				return -1
+
+			}
+
+		}
+
+		c := keys[key.Name](a, b)
+
+		if key.Reversed {
+			c = -c
+		}
+
+		if c != 0 {
+			return c
+		}
+
+	}
+
+	ra, rb := a.Prices.Instance.Region, b.Prices.Instance.Region
+
+	return cmp.Or(
+		cmp.Compare(ra.Provider, rb.Provider),
+		cmp.Compare(ra.Name, rb.Name),
+		cmp.Compare(a.Prices.Instance.Name, b.Prices.Instance.Name),
+	)
+
+}
+
+func (o Order) String() string {
+
+	names := make([]string, len(o))
+
+	for idx, key := range o {
+
+		names[idx] = key.Name
+
+		if key.Reversed {
+			names[idx] = "-" + key.Name
+		}
+
+	}
+
+	return strings.Join(names, ",")
+
+}
+
+// latency returns the average latency of the region of a result, unmeasured regions ranking last
+func latency(r *Result) float64 {
+
+	if l := &r.Prices.Instance.Region.Latency; l.Measured() {
+		return l.Avg
+	}
+
+	return math.Inf(1)
+
+}
@@ -0,0 +1,27 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOrder(t *testing.T) {
+
+	assert := assert.New(t)
+
+	order, err := ParseOrder("score, -latency,price,")
+
+	assert.NoError(err)
+	assert.Equal(Order{
+		{Name: "score"},
+		{Name: "latency", Reversed: true},
+		{Name: "price"},
+	}, order)
+	assert.Equal("score,-latency,price", order.String())
+
+	_, err = ParseOrder("score,speed")
+
+	assert.ErrorContains(err, `unknown sort key "speed"`)
+
+}
@@ -0,0 +1,226 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/yawn/instagpu/detect"
+	"github.com/yawn/instagpu/provider"
+	"golang.org/x/sync/errgroup"
+)
+
+// LOCAL is the vantage point of latencies measured while fetching a database
+const LOCAL = "local"
+
+// VantagePoint holds latencies to regions as measured from one origin, e.g. a CI runner
+type VantagePoint struct {
+	Name    string                    `json:"name"`
+	Regions map[string]detect.Latency `json:"regions"` // keyed by provider-region pairs
+}
+
+// LoadVantagePoint reads a vantage point from a file or an http(s) URL
+func LoadVantagePoint(ctx context.Context, from string) (*VantagePoint, error) {
+
+	var r io.ReadCloser
+
+	if strings.HasPrefix(from, "http://") || strings.HasPrefix(from, "https://") {
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, from, nil)
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid latency url %q", from)
+		}
+
+		res, err := http.DefaultClient.Do(req)
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to fetch latencies from %q", from)
+		}
+
+		if res.StatusCode != http.StatusOK {
+			res.Body.Close()
+			return nil, fmt.Errorf("failed to fetch latencies from %q: %s", from, res.Status)
+		}
+
+		r = res.Body
+
+	} else {
+
+		fh, err := os.Open(from)
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to open latencies file %q", from)
+		}
+
+		r = fh
+
+	}
+
+	defer r.Close()
+
+	var vp VantagePoint
+
+	if err := json.NewDecoder(r).Decode(&vp); err != nil {
+		return nil, errors.Wrapf(err, "corrupt latencies from %q", from)
+	}
+
+	if vp.Name == "" {
+		return nil, fmt.Errorf("latencies from %q lack a vantage point name", from)
+	}
+
+	return &vp, nil
+
+}
+
+// MeasureVantagePoint measures the latencies to all regions of providers from here, named name
+func MeasureVantagePoint(ctx context.Context, name string, options *Options, providers ...provider.Provider) (*VantagePoint, error) {
+
+	wg, ctx := errgroup.WithContext(ctx)
+
+	var (
+		mutex   sync.Mutex
+		prober  = options.Prober
+		samples = options.Samples
+		vp      = &VantagePoint{
+			Name:    name,
+			Regions: make(map[string]detect.Latency),
+		}
+	)
+
+	if prober == nil {
+		prober = detect.Probers[detect.PROBE]
+	}
+
+	if samples <= 0 {
+		samples = detect.SAMPLES
+	}
+
+	for _, p := range providers {
+
+		regions, err := p.Regions(ctx)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, region := range regions {
+
+			if options.Regions != nil && !options.Regions(region.Name) {
+				continue
+			}
+
+			wg.Go(func() error {
+
+				if err := region.MeasureLatency(ctx, prober, samples); err != nil {
+
+					if options.Strict {
+						return err
+					}
+
+					slog.Warn("failed to measure latency", slog.String("error", err.Error()))
+
+					return nil
+
+				}
+
+				mutex.Lock()
+				defer mutex.Unlock()
+
+				vp.Regions[pair(p.Name(), region.Name)] = region.Latency
+
+				return nil
+
+			})
+
+		}
+
+	}
+
+	if err := wg.Wait(); err != nil {
+		return nil, err
+	}
+
+	return vp, nil
+
+}
+
+// AddVantagePoint records the latencies of a vantage point, replacing earlier ones of the same name
+func (d *Database) AddVantagePoint(vp *VantagePoint) {
+
+	if d.Vantages == nil {
+		d.Vantages = make(map[string]map[string]detect.Latency)
+	}
+
+	d.Vantages[vp.Name] = vp.Regions
+
+}
+
+// KeepVantagePoints carries over the vantage points of a previous database, except for the LOCAL one which
+// is measured anew
+func (d *Database) KeepVantagePoints(previous *Database) {
+
+	for name, latencies := range previous.Vantages {
+
+		if name == LOCAL {
+			continue
+		}
+
+		if _, ok := d.Vantages[name]; ok {
+			continue
+		}
+
+		d.AddVantagePoint(&VantagePoint{
+			Name:    name,
+			Regions: latencies,
+		})
+
+	}
+
+}
+
+// UseVantagePoint sets the latency of all regions to the one measured from the named vantage point, regions
+// it lacks becoming unmeasured
+func (d *Database) UseVantagePoint(name string) error {
+
+	latencies, ok := d.Vantages[name]
+
+	if !ok {
+		return fmt.Errorf("unknown vantage point %q, known are %s", name, strings.Join(slices.Sorted(maps.Keys(d.Vantages)), ", "))
+	}
+
+	for _, prices := range d.Prices {
+
+		region := prices.Instance.Region
+		latency, ok := latencies[pair(region.Provider, region.Name)]
+
+		if !ok {
+
+			slog.Debug("region lacks latency from vantage point",
+				slog.String("region", region.Name),
+				slog.String("vantage", name),
+			)
+
+		}
+
+		region.Latency = latency
+
+	}
+
+	return nil
+
+}
+
+// pair returns the key of a region across providers
+func pair(provider, region string) string {
+	return fmt.Sprintf("%s-%s", provider, region)
+}
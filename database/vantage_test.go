@@ -0,0 +1,133 @@
+package database
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yawn/instagpu/detect"
+	"github.com/yawn/instagpu/provider/fake"
+)
+
+func TestLoadVantagePoint(t *testing.T) {
+
+	assert := assert.New(t)
+
+	const doc = `{"name":"ci","regions":{"fake-r0":{"avg":12.5,"samples":3}}}`
+
+	dir := t.TempDir()
+
+	for name, content := range map[string]string{
+		"ci.json":      doc,
+		"corrupt.json": `{"name":`,
+		"unnamed.json": `{"regions":{}}`,
+	} {
+		assert.NoError(os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if r.URL.Path != "/ci.json" {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Write([]byte(doc))
+
+	}))
+
+	defer server.Close()
+
+	for _, from := range []string{filepath.Join(dir, "ci.json"), server.URL + "/ci.json"} {
+
+		vp, err := LoadVantagePoint(context.Background(), from)
+
+		if assert.NoError(err, from) {
+			assert.Equal("ci", vp.Name)
+			assert.Equal(12.5, vp.Regions["fake-r0"].Avg)
+		}
+
+	}
+
+	for _, from := range []string{
+		filepath.Join(dir, "corrupt.json"),
+		filepath.Join(dir, "missing.json"),
+		filepath.Join(dir, "unnamed.json"),
+		server.URL + "/missing.json",
+	} {
+
+		_, err := LoadVantagePoint(context.Background(), from)
+		assert.Error(err, from)
+
+	}
+
+}
+
+func TestVantagePoints(t *testing.T) {
+
+	assert := assert.New(t)
+
+	p := fake.New()
+
+	p.AddRegion("r0")
+	p.AddRegion("r1")
+
+	for _, region := range []string{"r0", "r1"} {
+		p.AddInstance(region, &detect.Instance{
+			GPU:  &detect.GPU{},
+			Name: "i0",
+		}, &detect.Prices{AvailabilityZones: 1, Avg: 1})
+	}
+
+	options := &Options{
+		Prober: fake.Prober(10 * time.Millisecond),
+	}
+
+	db, err := New(context.Background(), options, p)
+	assert.NoError(err)
+
+	if assert.Contains(db.Vantages, LOCAL) {
+		assert.Len(db.Vantages[LOCAL], 2)
+		assert.Equal(10.0, db.Vantages[LOCAL]["fake-r0"].Avg)
+	}
+
+	vp, err := MeasureVantagePoint(context.Background(), "ci", &Options{
+		Prober: fake.Prober(30 * time.Millisecond),
+		Regions: func(name string) bool {
+			return name == "r1"
+		},
+	}, p)
+
+	assert.NoError(err)
+	assert.Equal("ci", vp.Name)
+	assert.Len(vp.Regions, 1)
+	assert.Equal(30.0, vp.Regions["fake-r1"].Avg)
+
+	db.AddVantagePoint(vp)
+
+	assert.EqualError(db.UseVantagePoint("laptop"), `unknown vantage point "laptop", known are ci, local`)
+
+	latencies := func() map[string]float64 {
+
+		latencies := make(map[string]float64)
+
+		for _, prices := range db.Prices {
+			latencies[prices.Instance.Region.Name] = prices.Instance.Region.Latency.Avg
+		}
+
+		return latencies
+
+	}
+
+	assert.NoError(db.UseVantagePoint("ci"))
+	assert.Equal(map[string]float64{"r0": 0, "r1": 30}, latencies()) // r0 is unmeasured from ci
+
+	assert.NoError(db.UseVantagePoint(LOCAL))
+	assert.Equal(map[string]float64{"r0": 10, "r1": 10}, latencies())
+
+}
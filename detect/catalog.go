@@ -0,0 +1,113 @@
+package detect
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+//go:embed catalog.json
+var embeddedCatalog []byte
+
+var (
+	catalog      Catalog
+	catalogMutex sync.RWMutex
+)
+
+// Catalog maps vendor-device tags to per-device performance data - NOTE: help with validation always welcome
+type Catalog map[string]*Device
+
+// Device is the performance data of a single accelerator, in dense TFLOPS (TOPS for INT8) and GB/s
+type Device struct {
+	BF16      *float64 `json:"bf16,omitempty"`
+	Bandwidth *float64 `json:"bandwidth,omitempty"`
+	FP16      *float64 `json:"fp16,omitempty"`
+	FP32      *float64 `json:"fp32,omitempty"`
+	FP8       *float64 `json:"fp8,omitempty"`
+	INT8      *float64 `json:"int8,omitempty"`
+	Source    string   `json:"source,omitempty"`
+}
+
+func init() {
+
+	if err := json.Unmarshal(embeddedCatalog, &catalog); err != nil {
+		panic(fmt.Sprintf("corrupt embedded catalog: %v", err))
+	}
+
+}
+
+// CatalogPath returns the path of the catalog in the user config dir
+func CatalogPath() (string, error) {
+
+	dir, err := os.UserConfigDir()
+
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "instagpu", "catalog.json"), nil
+
+}
+
+// CurrentCatalog returns a copy of the catalog in use
+func CurrentCatalog() Catalog {
+
+	catalogMutex.RLock()
+	defer catalogMutex.RUnlock()
+
+	c := make(Catalog, len(catalog))
+
+	for tag, device := range catalog {
+		c[tag] = device
+	}
+
+	return c
+
+}
+
+// LoadCatalog reads a catalog from path
+func LoadCatalog(path string) (Catalog, error) {
+
+	raw, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read catalog %q", path)
+	}
+
+	var c Catalog
+
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return nil, errors.Wrapf(err, "corrupt catalog in file %q", path)
+	}
+
+	return c, nil
+
+}
+
+// UseCatalog overrides and extends the catalog in use with the entries of c
+func UseCatalog(c Catalog) {
+
+	catalogMutex.Lock()
+	defer catalogMutex.Unlock()
+
+	for tag, device := range c {
+		catalog[tag] = device
+	}
+
+}
+
+func lookup(tag string) (*Device, bool) {
+
+	catalogMutex.RLock()
+	defer catalogMutex.RUnlock()
+
+	device, ok := catalog[tag]
+
+	return device, ok
+
+}
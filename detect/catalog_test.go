@@ -0,0 +1,56 @@
+package detect
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCatalog(t *testing.T) {
+
+	assert := assert.New(t)
+
+	previous := CurrentCatalog()
+
+	t.Cleanup(func() {
+		catalog = previous
+	})
+
+	gpu := &GPU{Count: 2, Name: "A10G", Vendor: "NVIDIA"}
+
+	gpu.MeasureTFLOPS()
+
+	require.NotNil(t, gpu.FP32)
+	assert.InDelta(63.04, *gpu.FP32, 1e-9, "scaled by count")
+	assert.InDelta(1200, *gpu.Bandwidth, 1e-9, "scaled by count")
+	assert.Nil(gpu.FP8, "unknown metric")
+
+	path := filepath.Join(t.TempDir(), "catalog.json")
+
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"NVIDIA-A10G": {"fp32": 30},
+		"Vendor-Unknown": {"bf16": 1}
+	}`), 0644))
+
+	c, err := LoadCatalog(path)
+
+	require.NoError(t, err)
+
+	UseCatalog(c)
+
+	gpu.MeasureTFLOPS()
+
+	assert.InDelta(60, *gpu.FP32, 1e-9, "overridden")
+	assert.Nil(gpu.Bandwidth, "overridden entries replace the embedded ones")
+
+	gpu = &GPU{Count: 1, Name: "Unknown", Vendor: "Vendor"}
+
+	gpu.MeasureTFLOPS()
+
+	assert.InDelta(1, *gpu.BF16, 1e-9, "extended")
+	assert.Nil(gpu.FP32)
+
+}
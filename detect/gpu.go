@@ -6,48 +6,67 @@ import (
 	"strings"
 )
 
-// devices maps known vendor-device tags to FP32 TFLOP
-var devices = map[string]float64{ // NOTE: help with validation always welcome
-	"AMD-Radeon Pro V520": 7.373, // https://www.techpowerup.com/gpu-specs/radeon-pro-v520.c3755
-	"NVIDIA-A100":         19.49, // https://www.techpowerup.com/gpu-specs/a100-sxm4-40-gb.c3506
-	"NVIDIA-A10G":         31.52, // https://www.techpowerup.com/gpu-specs/a10g.c3798
-	"NVIDIA-H100":         66.91, // https://www.techpowerup.com/gpu-specs/h100-sxm5-80-gb.c3900
-	"NVIDIA-K80":          4.113, // https://www.techpowerup.com/gpu-specs/tesla-k80.c2616
-	"NVIDIA-L4":           30.29, // https://www.techpowerup.com/gpu-specs/l4.c4091
-	"NVIDIA-L40S":         91.61, // https://www.techpowerup.com/gpu-specs/l40s.c4173
-	"NVIDIA-M60":          4.825, // https://www.techpowerup.com/gpu-specs/tesla-m60.c2760
-	"NVIDIA-T4":           8.141, // https://www.techpowerup.com/gpu-specs/tesla-t4.c3316
-	"NVIDIA-T4g":          8.141, // https://www.techpowerup.com/gpu-specs/tesla-t4g.c4134
-	"NVIDIA-V100":         16.35, // https://www.techpowerup.com/gpu-specs/tesla-v100-sxm2-16-gb.c3471
-}
+// kinds of accelerators
+const (
+	KindGPU       = "gpu"
+	KindInference = "inference"
+	KindNeuron    = "neuron"
+)
 
+// GPU describes the accelerators of an instance - despite the name, this includes non-GPU accelerators
 type GPU struct {
-	Count  uint     `json:"count"`
-	FP32   *float64 `json:"fp32 "` // TFLOPS performance
-	Memory uint64   `json:"memory"`
-	Name   string   `json:"name"`
-	Vendor string   `json:"vendor"`
+	BF16      *float64 `json:"bf16,omitempty"`      // TFLOPS performance
+	Bandwidth *float64 `json:"bandwidth,omitempty"` // memory bandwidth in GB/s
+	Count     uint     `json:"count"`
+	FP16      *float64 `json:"fp16,omitempty"` // TFLOPS performance
+	FP32      *float64 `json:"fp32,omitempty"` // TFLOPS performance
+	FP8       *float64 `json:"fp8,omitempty"`  // TFLOPS performance
+	INT8      *float64 `json:"int8,omitempty"` // TOPS performance
+	Kind      string   `json:"kind"`
+	Memory    uint64   `json:"memory"`
+	Name      string   `json:"name"`
+	Vendor    string   `json:"vendor"`
 }
 
+// MeasureTFLOPS annotates the GPU with the performance data of all devices, as found in the catalog
 func (g *GPU) MeasureTFLOPS() {
 
-	tag := fmt.Sprintf("%s-%s", g.Vendor, g.Name)
-
-	tflops, ok := devices[tag]
+	device, ok := lookup(g.Tag())
 
 	if !ok {
 
 		slog.Warn("no device data for vendor tag - please consider contributing a pull-request",
-			slog.String("tag", tag),
+			slog.String("tag", g.Tag()),
 		)
 
-	} else {
+		device = new(Device)
+
+	}
+
+	scale := func(value *float64) *float64 {
+
+		if value == nil {
+			return nil
+		}
 
-		tflops = tflops * float64(g.Count)
-		g.FP32 = &tflops
+		total := *value * float64(g.Count)
+
+		return &total
 
 	}
 
+	g.BF16 = scale(device.BF16)
+	g.Bandwidth = scale(device.Bandwidth)
+	g.FP16 = scale(device.FP16)
+	g.FP32 = scale(device.FP32)
+	g.FP8 = scale(device.FP8)
+	g.INT8 = scale(device.INT8)
+
+}
+
+// Tag identifies the device in the catalog
+func (g *GPU) Tag() string {
+	return fmt.Sprintf("%s-%s", g.Vendor, g.Name)
 }
 
 func (g *GPU) String() string {
@@ -56,6 +75,10 @@ func (g *GPU) String() string {
 
 	fmt.Fprintf(&b, "🎨 %dx%s-%s", g.Count, g.Vendor, g.Name)
 
+	if g.Kind != "" && g.Kind != KindGPU {
+		fmt.Fprintf(&b, " (%s)", g.Kind)
+	}
+
 	if g.FP32 != nil {
 		fmt.Fprintf(&b, "\t⚡ %.2f fp32", *g.FP32)
 	}
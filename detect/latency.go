@@ -0,0 +1,222 @@
+package detect
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	probing "github.com/prometheus-community/pro-bing"
+)
+
+const (
+	// PROBE is the default latency prober
+	PROBE = "auto"
+
+	// SAMPLES is the default number of latency samples per region
+	SAMPLES = 3
+
+	// TIMEOUT is the maximum time waited for a single latency sample
+	TIMEOUT = 2 * time.Second
+)
+
+// Prober measures round-trip times to an endpoint, given as host or host:port - it returns the times of all
+// answered samples, failing only if none was answered
+type Prober interface {
+	Name() string
+	Probe(ctx context.Context, endpoint string, samples int) ([]time.Duration, error)
+}
+
+// Probers are all latency probers by name
+var Probers = map[string]Prober{
+	"auto":  Auto{ICMP{}, TCP{}, HTTPS{}},
+	"https": HTTPS{},
+	"icmp":  ICMP{},
+	"tcp":   TCP{},
+}
+
+// LookupProber returns the prober of name
+func LookupProber(name string) (Prober, error) {
+
+	prober, ok := Probers[name]
+
+	if !ok {
+		return nil, fmt.Errorf("unknown latency probe %q, expected one of %s", name, strings.Join(slices.Sorted(maps.Keys(Probers)), ", "))
+	}
+
+	return prober, nil
+
+}
+
+// Auto probes with the first of its probers that succeeds, e.g. falling back to TCP where ICMP is blocked
+type Auto []Prober
+
+func (a Auto) Name() string {
+	return "auto"
+}
+
+func (a Auto) Probe(ctx context.Context, endpoint string, samples int) ([]time.Duration, error) {
+
+	var errs []string
+
+	for _, prober := range a {
+
+		rtts, err := prober.Probe(ctx, endpoint, samples)
+
+		if err == nil {
+			return rtts, nil
+		}
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		slog.Debug("latency probe failed, falling back",
+			slog.String("endpoint", endpoint),
+			slog.String("error", err.Error()),
+			slog.String("probe", prober.Name()),
+		)
+
+		errs = append(errs, err.Error())
+
+	}
+
+	return nil, fmt.Errorf("all latency probes failed: %s", strings.Join(errs, "; "))
+
+}
+
+// HTTPS measures the time of TCP connects including TLS handshakes, at port 443 unless given
+type HTTPS struct {
+	Config *tls.Config // defaults to verifying the endpoint host against system roots
+}
+
+func (h HTTPS) Name() string {
+	return "https"
+}
+
+func (h HTTPS) Probe(ctx context.Context, endpoint string, samples int) ([]time.Duration, error) {
+
+	dialer := &tls.Dialer{
+		Config: h.Config,
+		NetDialer: &net.Dialer{
+			Timeout: TIMEOUT,
+		},
+	}
+
+	return dial(ctx, h.Name(), dialer.DialContext, endpoint, samples)
+
+}
+
+// ICMP measures round-trip times of echo requests, which needs raw-socket privileges or unprivileged ping
+// enabled by the kernel
+type ICMP struct{}
+
+func (i ICMP) Name() string {
+	return "icmp"
+}
+
+func (i ICMP) Probe(ctx context.Context, endpoint string, samples int) ([]time.Duration, error) {
+
+	host := endpoint
+
+	if h, _, err := net.SplitHostPort(endpoint); err == nil {
+		host = h
+	}
+
+	pinger, err := probing.NewPinger(host)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to setup pinging %q", host)
+	}
+
+	pinger.Count = samples
+	pinger.Timeout = time.Duration(samples) * TIMEOUT
+
+	if err := pinger.RunWithContext(ctx); err != nil {
+		return nil, errors.Wrapf(err, "failed to execute pinging %q", host)
+	}
+
+	stats := pinger.Statistics()
+
+	if len(stats.Rtts) == 0 {
+		return nil, fmt.Errorf("no replies pinging %q", host)
+	}
+
+	return stats.Rtts, nil
+
+}
+
+// TCP measures the time of TCP connects, at port 443 unless given
+type TCP struct{}
+
+func (t TCP) Name() string {
+	return "tcp"
+}
+
+func (t TCP) Probe(ctx context.Context, endpoint string, samples int) ([]time.Duration, error) {
+
+	dialer := &net.Dialer{
+		Timeout: TIMEOUT,
+	}
+
+	return dial(ctx, t.Name(), dialer.DialContext, endpoint, samples)
+
+}
+
+// dial measures the time of samples sequential connections to endpoint, skipping failed ones
+func dial(ctx context.Context, name string, fn func(ctx context.Context, network, address string) (net.Conn, error), endpoint string, samples int) ([]time.Duration, error) {
+
+	address := endpoint
+
+	if _, _, err := net.SplitHostPort(endpoint); err != nil {
+		address = net.JoinHostPort(endpoint, "443")
+	}
+
+	var (
+		last error
+		rtts []time.Duration
+	)
+
+	for range samples {
+
+		start := time.Now()
+
+		conn, err := fn(ctx, "tcp", address)
+
+		if err != nil {
+
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+
+			var dns *net.DNSError
+
+			if errors.As(err, &dns) {
+				return nil, errors.Wrapf(err, "failed to %s probe %q", name, address) // retrying won't resolve it
+			}
+
+			last = err
+
+			continue
+
+		}
+
+		rtts = append(rtts, time.Since(start))
+
+		conn.Close()
+
+	}
+
+	if len(rtts) == 0 {
+		return nil, errors.Wrapf(last, "failed to %s probe %q", name, address)
+	}
+
+	return rtts, nil
+
+}
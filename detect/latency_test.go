@@ -0,0 +1,192 @@
+package detect
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stub is a prober returning fixed round-trip times or an error
+type stub struct {
+	err  error
+	rtts []time.Duration
+}
+
+func (s stub) Name() string {
+	return "stub"
+}
+
+func (s stub) Probe(ctx context.Context, endpoint string, samples int) ([]time.Duration, error) {
+	return s.rtts, s.err
+}
+
+func listen(t *testing.T) string {
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+
+	require.NoError(t, err)
+
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+
+		for {
+
+			conn, err := l.Accept()
+
+			if err != nil {
+				return
+			}
+
+			conn.Close()
+
+		}
+
+	}()
+
+	return l.Addr().String()
+
+}
+
+func TestProbers(t *testing.T) {
+
+	ctx := context.Background()
+
+	t.Run("tcp", func(t *testing.T) {
+
+		rtts, err := TCP{}.Probe(ctx, listen(t), 3)
+
+		assert.NoError(t, err)
+		assert.Len(t, rtts, 3)
+
+	})
+
+	t.Run("https", func(t *testing.T) {
+
+		srv := httptest.NewTLSServer(http.NotFoundHandler())
+		defer srv.Close()
+
+		prober := HTTPS{
+			Config: srv.Client().Transport.(*http.Transport).TLSClientConfig,
+		}
+
+		rtts, err := prober.Probe(ctx, srv.Listener.Addr().String(), 2)
+
+		assert.NoError(t, err)
+		assert.Len(t, rtts, 2)
+
+		_, err = HTTPS{}.Probe(ctx, srv.Listener.Addr().String(), 1)
+
+		assert.ErrorContains(t, err, "failed to https probe", "untrusted certificate")
+
+	})
+
+	t.Run("closed", func(t *testing.T) {
+
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+
+		require.NoError(t, err)
+
+		address := l.Addr().String()
+		l.Close()
+
+		_, err = TCP{}.Probe(ctx, address, 1)
+
+		assert.ErrorContains(t, err, "failed to tcp probe")
+
+	})
+
+	t.Run("auto", func(t *testing.T) {
+
+		assert := assert.New(t)
+
+		rtts, err := Auto{stub{err: fmt.Errorf("blocked")}, TCP{}}.Probe(ctx, listen(t), 2)
+
+		assert.NoError(err, "falls back")
+		assert.Len(rtts, 2)
+
+		_, err = Auto{stub{err: fmt.Errorf("blocked")}, stub{err: fmt.Errorf("refused")}}.Probe(ctx, "localhost", 1)
+
+		assert.ErrorContains(err, "all latency probes failed: blocked; refused")
+
+	})
+
+	t.Run("lookup", func(t *testing.T) {
+
+		assert := assert.New(t)
+
+		prober, err := LookupProber(PROBE)
+
+		assert.NoError(err)
+		assert.Equal("auto", prober.Name())
+
+		_, err = LookupProber("udp")
+
+		assert.ErrorContains(err, `unknown latency probe "udp", expected one of auto, https, icmp, tcp`)
+
+	})
+
+}
+
+func TestMeasureLatency(t *testing.T) {
+
+	assert := assert.New(t)
+
+	region := &Region{Name: "eu-west-1"}
+
+	err := region.MeasureLatency(context.Background(), stub{rtts: []time.Duration{
+		10 * time.Millisecond,
+		30 * time.Millisecond,
+		20 * time.Millisecond,
+	}}, 3)
+
+	assert.NoError(err)
+	assert.EqualValues(20, region.Latency.Avg)
+	assert.EqualValues(10, region.Latency.Min)
+	assert.EqualValues(30, region.Latency.Max)
+
+	err = region.MeasureLatency(context.Background(), stub{err: fmt.Errorf("blocked")}, 3)
+
+	assert.ErrorContains(err, `failed to measure latency of region "eu-west-1": blocked`)
+
+}
+
+func TestNewLatency(t *testing.T) {
+
+	assert := assert.New(t)
+
+	var rtts []time.Duration
+
+	for ms := 1; ms <= 19; ms++ {
+		rtts = append(rtts, time.Duration(ms)*time.Millisecond+500*time.Microsecond)
+	}
+
+	latency := NewLatency(rtts, 20)
+
+	assert.InDelta(10.5, latency.Avg, 1e-9, "sub-millisecond precision")
+	assert.InDelta(5, latency.Loss, 1e-9)
+	assert.InDelta(1.5, latency.Min, 1e-9)
+	assert.InDelta(19.5, latency.Max, 1e-9)
+	assert.InDelta(10.5, latency.P50, 1e-9)
+	assert.InDelta(19.5, latency.P95, 1e-9)
+	assert.Equal(20, latency.Samples)
+	assert.InDelta(5.477, latency.StdDev, 1e-3)
+
+	assert.Zero(NewLatency(nil, 3), "unmeasured")
+
+	region := &Region{
+		Latency:  NewLatency(rtts, 20),
+		Name:     "eu-west-1",
+		Provider: "aws",
+	}
+
+	assert.Equal("📍 aws-eu-west-1\t🐢 10.5ms ±5.5ms 📉 5%", region.String())
+
+}
@@ -0,0 +1,48 @@
+package detect
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Machine is an instance launched by instagpu
+type Machine struct {
+	ID       string    `json:"id"`
+	Instance string    `json:"instance"`
+	Launched time.Time `json:"launched"`
+	MaxPrice float64   `json:"max_price"` // USD / h
+	Price    float64   `json:"price"`     // current spot price in USD / h, if known
+	Region   *Region   `json:"region"`
+	State    string    `json:"state"`
+	Zone     string    `json:"zone"`
+}
+
+// Cost estimates the accrued cost in USD at the current spot price
+func (m *Machine) Cost(now time.Time) float64 {
+	return m.Uptime(now).Hours() * m.Price
+}
+
+// Uptime returns the duration since launch
+func (m *Machine) Uptime(now time.Time) time.Duration {
+	return now.Sub(m.Launched)
+}
+
+func (m *Machine) String() string {
+
+	var (
+		b   strings.Builder
+		now = time.Now()
+	)
+
+	fmt.Fprintf(&b, "🖥️ %s", m.ID)
+	fmt.Fprintf(&b, "\t📍 %s-%s", m.Region.Provider, m.Zone)
+	fmt.Fprintf(&b, "\t🏷️ %s", m.Instance)
+	fmt.Fprintf(&b, "\t🚦 %s", m.State)
+	fmt.Fprintf(&b, "\t⏱️ %s", m.Uptime(now).Round(time.Minute))
+	fmt.Fprintf(&b, "\t💰 %.2f USD/h", m.Price)
+	fmt.Fprintf(&b, "\t💸 %.2f USD", m.Cost(now))
+
+	return b.String()
+
+}
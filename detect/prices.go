@@ -1,27 +1,278 @@
 package detect
 
 import (
+	"cmp"
 	"fmt"
+	"maps"
+	"math"
+	"slices"
 	"strings"
+	"time"
 )
 
 type Prices struct {
-	AvailablityZones uint      `json:"availability_zones"`
-	Avg              float64   `json:"avg"`
-	Instance         *Instance `json:"instance"`
-	Max              float64   `json:"max"`
-	Min              float64   `json:"min"`
+	AvailabilityZones uint      `json:"availability_zones"`
+	Avg               float64   `json:"avg"` // time-weighted
+	Current           float64   `json:"current"`
+	Instance          *Instance `json:"instance"`
+	Max               float64   `json:"max"`
+	Median            float64   `json:"median"`
+	Min               float64   `json:"min"`
+	P90               float64   `json:"p90"`
+	StdDev            float64   `json:"stddev"`
+	Trend             float64   `json:"trend"`           // USD / h per day
+	Zones             []*Zone   `json:"zones,omitempty"` // sorted by name
 }
 
-func (p *Prices) PTGPIndex() float64 {
+// SpotPrice is a change of the spot price in an availability zone, holding until the next change there
+type SpotPrice struct {
+	AvailabilityZone string    `json:"availability_zone"`
+	Price            float64   `json:"price"`
+	Time             time.Time `json:"time"`
+}
+
+// Zone holds the price history and statistics of a single availability zone
+type Zone struct {
+	Avg     float64     `json:"avg"` // time-weighted
+	Current float64     `json:"current"`
+	History []SpotPrice `json:"history"`
+	ID      string      `json:"id,omitempty"` // stable across accounts, unlike names
+	Max     float64     `json:"max"`
+	Median  float64     `json:"median"`
+	Min     float64     `json:"min"`
+	Name    string      `json:"name"`
+	P90     float64     `json:"p90"`
+	StdDev  float64     `json:"stddev"`
+	Trend   float64     `json:"trend"` // USD / h per day
+}
+
+// segment is a price holding from one to another number of days after the start of a window
+type segment struct {
+	from, price, to float64
+}
+
+// NewPrices computes statistics over the price history of instance between start and end, weighting each price
+// by how long it held, both across and per availability zone - it returns nil without any history, i.e. if the
+// instance is not available as spot
+func NewPrices(instance *Instance, history []SpotPrice, start, end time.Time) *Prices {
+
+	if len(history) == 0 {
+		return nil
+	}
+
+	var (
+		current  float64
+		days     = func(t time.Time) float64 { return t.Sub(start).Hours() / 24 }
+		segments []segment
+		zones    = make(map[string][]SpotPrice)
+	)
+
+	for _, price := range history {
+		zones[price.AvailabilityZone] = append(zones[price.AvailabilityZone], price)
+	}
+
+	prices := &Prices{
+		AvailabilityZones: uint(len(zones)),
+		Instance:          instance,
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(zones)) {
+
+		var (
+			history = zones[name]
+			own     []segment
+		)
+
+		slices.SortFunc(history, func(a, b SpotPrice) int {
+			return a.Time.Compare(b.Time)
+		})
+
+		for idx, price := range history {
+
+			from, to := max(days(price.Time), 0), days(end)
+
+			if idx < len(history)-1 {
+				to = days(history[idx+1].Time)
+			}
+
+			if to <= from && idx < len(history)-1 {
+				continue // superseded before start
+			}
+
+			own = append(own, segment{
+				from:  from,
+				price: price.Price,
+				to:    max(to, from),
+			})
+
+		}
+
+		zone := summarize(own)
+
+		zone.Current = history[len(history)-1].Price
+		zone.History = history
+		zone.Name = name
+
+		current += zone.Current
+		segments = append(segments, own...)
+
+		prices.Zones = append(prices.Zones, zone)
+
+	}
+
+	all := summarize(segments)
+
+	prices.Avg = all.Avg
+	prices.Current = current / float64(len(zones))
+	prices.Max = all.Max
+	prices.Median = all.Median
+	prices.Min = all.Min
+	prices.P90 = all.P90
+	prices.StdDev = all.StdDev
+	prices.Trend = all.Trend
+
+	return prices
+
+}
+
+// summarize computes the statistics of a zone over segments, lacking its name, history and current price
+func summarize(segments []segment) *Zone {
+
+	var span, total float64
+
+	for _, s := range segments {
+		span += s.to - s.from
+	}
+
+	// weight returns the duration of a segment, or weighs all equally if the history spans no time
+	weight := func(s segment) float64 {
+
+		if span == 0 {
+			return 1
+		}
+
+		return s.to - s.from
+
+	}
+
+	for _, s := range segments {
+		total += weight(s)
+	}
+
+	segments = slices.Clone(segments)
+
+	slices.SortFunc(segments, func(a, b segment) int {
+		return cmp.Compare(a.price, b.price)
+	})
+
+	// percentile picks the price holding at the p-th fraction of the total time
+	percentile := func(p float64) float64 {
+
+		var sum float64
+
+		for _, s := range segments {
+
+			if sum += weight(s); sum >= p*total {
+				return s.price
+			}
+
+		}
+
+		return segments[len(segments)-1].price
+
+	}
+
+	zone := &Zone{
+		Max:    segments[len(segments)-1].price,
+		Median: percentile(0.5),
+		Min:    segments[0].price,
+		P90:    percentile(0.9),
+	}
+
+	// least squares over the continuous step function of the segments, integrating t and t² per segment
+	var sum, squares, t, tt, pt float64
+
+	for _, s := range segments {
+		sum += s.price * weight(s)
+		t += (s.to*s.to - s.from*s.from) / 2
+		tt += (s.to*s.to*s.to - s.from*s.from*s.from) / 3
+		pt += s.price * (s.to*s.to - s.from*s.from) / 2
+	}
+
+	zone.Avg = sum / total
+
+	for _, s := range segments {
+		squares += weight(s) * (s.price - zone.Avg) * (s.price - zone.Avg)
+	}
+
+	zone.StdDev = math.Sqrt(squares / total)
+
+	if d := total*tt - t*t; d > 1e-12 {
+		zone.Trend = (total*pt - t*sum) / d
+	}
+
+	return zone
+
+}
+
+// AtZone returns a copy of the prices with the statistics of zone in place of the ones across zones
+func (p *Prices) AtZone(zone *Zone) *Prices {
+
+	prices := *p
+
+	prices.Avg = zone.Avg
+	prices.Current = zone.Current
+	prices.Max = zone.Max
+	prices.Median = zone.Median
+	prices.Min = zone.Min
+	prices.P90 = zone.P90
+	prices.StdDev = zone.StdDev
+	prices.Trend = zone.Trend
+
+	return &prices
+
+}
+
+// InZones returns a copy of the prices limited to the zones kept, e.g. for choosing the cheapest one among them
+func (p *Prices) InZones(keep func(zone *Zone) bool) *Prices {
+
+	prices := *p
+
+	prices.Zones = slices.DeleteFunc(slices.Clone(p.Zones), func(zone *Zone) bool {
+		return !keep(zone)
+	})
+
+	return &prices
+
+}
+
+// Cheapest returns the zone with the lowest average price, or nil if zones are unknown
+func (p *Prices) Cheapest() *Zone {
+
+	var cheapest *Zone
+
+	for _, zone := range p.Zones {
+
+		if cheapest == nil || zone.Avg < cheapest.Avg {
+			cheapest = zone
+		}
+
+	}
+
+	return cheapest
+
+}
+
+// PTGPIndex returns the price-to-gpu-performance index, or false if performance data or prices are missing
+func (p *Prices) PTGPIndex() (float64, bool) {
 
 	perf := p.Instance.GPU.FP32
 
-	if perf == nil {
-		panic("cannot determine price-to-gpu-performance index without GPU performance data")
+	if perf == nil || p.Avg <= 0 {
+		return 0, false
 	}
 
-	return *perf / p.Avg
+	return *perf / p.Avg, true
 
 }
 
@@ -34,8 +285,24 @@ func (p *Prices) String() string {
 	fmt.Fprintf(&b, "💰 %.2f USD/h", p.Avg)
 	fmt.Fprintf(&b, "\t▼ %.2f USD/h", p.Min)
 	fmt.Fprintf(&b, "\t▲ %.2f USD/h", p.Max)
-	fmt.Fprintf(&b, "\t🧩 %d AZs", p.AvailablityZones)
+	fmt.Fprintf(&b, "\t⏱ %.2f USD/h", p.Current)
+	fmt.Fprintf(&b, "\t%s %+.3f USD/h/d", trend(p.Trend), p.Trend)
+	fmt.Fprintf(&b, "\t🧩 %d AZs", p.AvailabilityZones)
 
 	return b.String()
 
 }
+
+// trend returns an emoji for the direction of a price trend
+func trend(slope float64) string {
+
+	switch {
+	case slope > 0:
+		return "📈"
+	case slope < 0:
+		return "📉"
+	}
+
+	return "➡️"
+
+}
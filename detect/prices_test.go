@@ -0,0 +1,138 @@
+package detect
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPRTGIndex(t *testing.T) {
+
+	assert := assert.New(t)
+
+	var (
+		perf     float64 = 100
+		instance         = &Instance{GPU: &GPU{FP32: &perf}}
+		prices           = &Prices{Avg: 1, Instance: instance}
+	)
+
+	index, ok := prices.PTGPIndex()
+
+	assert.True(ok)
+	assert.EqualValues(100, index)
+
+	prices.Avg = 2
+
+	index, ok = prices.PTGPIndex()
+
+	assert.True(ok)
+	assert.EqualValues(50, index)
+
+	instance.GPU.FP32 = nil
+
+	_, ok = prices.PTGPIndex()
+
+	assert.False(ok, "missing performance data")
+
+}
+
+func TestNewPrices(t *testing.T) {
+
+	var (
+		instance = &Instance{GPU: &GPU{}}
+		start    = time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC)
+		end      = start.Add(4 * 24 * time.Hour)
+		at       = func(days int) time.Time { return start.Add(time.Duration(days) * 24 * time.Hour) }
+	)
+
+	t.Run("unavailable", func(t *testing.T) {
+		assert.Nil(t, NewPrices(instance, nil, start, end))
+		assert.Nil(t, (&Prices{}).Cheapest(), "zones unknown")
+	})
+
+	t.Run("weighted", func(t *testing.T) {
+
+		assert := assert.New(t)
+
+		// a short spike must not count as much as the price holding for days
+		prices := NewPrices(instance, []SpotPrice{
+			{AvailabilityZone: "a", Price: 1, Time: at(-1)},
+			{AvailabilityZone: "a", Price: 9, Time: at(3)},
+			{AvailabilityZone: "a", Price: 1, Time: at(3).Add(time.Hour)},
+		}, start, end)
+
+		assert.EqualValues(1, prices.AvailabilityZones)
+		assert.InDelta(1+8.0/96, prices.Avg, 1e-9)
+		assert.Equal(1.0, prices.Current)
+		assert.Equal(1.0, prices.Median)
+		assert.Equal(1.0, prices.Min)
+		assert.Equal(9.0, prices.Max)
+		assert.Equal(1.0, prices.P90)
+		assert.Same(instance, prices.Instance)
+
+	})
+
+	t.Run("trend", func(t *testing.T) {
+
+		assert := assert.New(t)
+
+		prices := NewPrices(instance, []SpotPrice{
+			{AvailabilityZone: "a", Price: 4, Time: at(0)},
+			{AvailabilityZone: "a", Price: 2, Time: at(2)},
+			{AvailabilityZone: "b", Price: 3, Time: at(0)},
+		}, start, end)
+
+		assert.EqualValues(2, prices.AvailabilityZones)
+		assert.InDelta(3, prices.Avg, 1e-9)
+		assert.InDelta(2.5, prices.Current, 1e-9)
+		assert.Equal(3.0, prices.Median)
+		assert.Equal(4.0, prices.P90)
+		assert.InDelta(math.Sqrt(0.5), prices.StdDev, 1e-9)
+		assert.Less(prices.Trend, 0.0, "falling")
+
+		if assert.Len(prices.Zones, 2) {
+
+			a, b := prices.Zones[0], prices.Zones[1]
+
+			assert.Equal("a", a.Name)
+			assert.Len(a.History, 2)
+			assert.InDelta(3, a.Avg, 1e-9)
+			assert.Equal(2.0, a.Current)
+			assert.InDelta(1, a.StdDev, 1e-9)
+			assert.Less(a.Trend, 0.0)
+
+			assert.Equal("b", b.Name)
+			assert.InDelta(3, b.Avg, 1e-9)
+			assert.Equal(0.0, b.Trend)
+
+			assert.Same(a, prices.Cheapest(), "first of equally cheap zones")
+
+			at := prices.AtZone(a)
+
+			assert.Equal(2.0, at.Current)
+			assert.Equal(2.0, at.Min)
+			assert.InDelta(1, at.StdDev, 1e-9)
+			assert.InDelta(2.5, prices.Current, 1e-9, "unchanged")
+
+		}
+
+	})
+
+	t.Run("instant", func(t *testing.T) {
+
+		assert := assert.New(t)
+
+		// without any time passing, prices weigh equally
+		prices := NewPrices(instance, []SpotPrice{
+			{AvailabilityZone: "a", Price: 1, Time: end},
+			{AvailabilityZone: "b", Price: 3, Time: end},
+		}, start, end)
+
+		assert.InDelta(2, prices.Avg, 1e-9)
+		assert.Equal(0.0, prices.Trend)
+
+	})
+
+}
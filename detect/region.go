@@ -4,46 +4,99 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"math"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
-	probing "github.com/prometheus-community/pro-bing"
 )
 
+// Latency holds round-trip statistics in ms over all samples answered
+type Latency struct {
+	Avg     float64 `json:"avg"`
+	Loss    float64 `json:"loss"` // unanswered samples in percent
+	Max     float64 `json:"max"`
+	Min     float64 `json:"min"`
+	P50     float64 `json:"p50"`
+	P95     float64 `json:"p95"`
+	Samples int     `json:"samples"` // samples sent
+	StdDev  float64 `json:"stddev"`
+}
+
+// NewLatency computes statistics over rtts, answered out of samples sent
+func NewLatency(rtts []time.Duration, samples int) Latency {
+
+	var latency Latency
+
+	if len(rtts) == 0 || samples <= 0 {
+		return latency
+	}
+
+	ms := make([]float64, len(rtts))
+
+	for idx, rtt := range rtts {
+		ms[idx] = float64(rtt) / float64(time.Millisecond)
+	}
+
+	slices.Sort(ms)
+
+	// percentile picks the nearest rank
+	percentile := func(p float64) float64 {
+		return ms[max(int(math.Ceil(p*float64(len(ms))))-1, 0)]
+	}
+
+	var sum, squares float64
+
+	for _, v := range ms {
+		sum += v
+	}
+
+	latency.Avg = sum / float64(len(ms))
+
+	for _, v := range ms {
+		squares += (v - latency.Avg) * (v - latency.Avg)
+	}
+
+	latency.Loss = 100 * float64(samples-len(ms)) / float64(samples)
+	latency.Max = ms[len(ms)-1]
+	latency.Min = ms[0]
+	latency.P50 = percentile(0.5)
+	latency.P95 = percentile(0.95)
+	latency.Samples = samples
+	latency.StdDev = math.Sqrt(squares / float64(len(ms)))
+
+	return latency
+
+}
+
+// Measured reports if any sample was answered, unmeasured latencies being all zero
+func (l *Latency) Measured() bool {
+	return l.Avg > 0
+}
+
 type Region struct {
-	Endpoint string
-	Latency  struct {
-		Avg uint64 `json:"avg"`
-		Min uint64 `json:"min"`
-		Max uint64 `json:"max"`
-	} `json:"latency"`
-	Name     string `json:"name"`
-	Provider string `json:"provider"`
+	Endpoint string  `json:"endpoint"`
+	Latency  Latency `json:"latency"`
+	Name     string  `json:"name"`
+	Provider string  `json:"provider"`
 }
 
-func (r *Region) MeasureLatency(ctx context.Context) error {
+// MeasureLatency measures the round-trip latency to the region endpoint with samples probes
+func (r *Region) MeasureLatency(ctx context.Context, prober Prober, samples int) error {
 
 	slog.Debug("measuring latency",
+		slog.String("probe", prober.Name()),
 		slog.String("region", r.Name),
 	)
 
-	pinger, err := probing.NewPinger(r.Endpoint)
+	rtts, err := prober.Probe(ctx, r.Endpoint, samples)
 
 	if err != nil {
-		return errors.Wrapf(err, "failed to setup pinging region %q", r.Name)
-	}
-
-	pinger.Count = 3
-
-	if err := pinger.RunWithContext(ctx); err != nil {
-		return errors.Wrapf(err, "failed to execute pinging region %q", r.Name)
+		return errors.Wrapf(err, "failed to measure latency of region %q", r.Name)
 	}
 
-	stats := pinger.Statistics()
-
-	r.Latency.Avg = uint64(stats.AvgRtt.Milliseconds())
-	r.Latency.Max = uint64(stats.MaxRtt.Milliseconds())
-	r.Latency.Min = uint64(stats.MinRtt.Milliseconds())
+	r.Latency = NewLatency(rtts, samples)
 
 	return nil
 
@@ -54,7 +107,11 @@ func (r *Region) String() string {
 	var b strings.Builder
 
 	fmt.Fprintf(&b, "📍 %s-%s", r.Provider, r.Name)
-	fmt.Fprintf(&b, "\t🐢 %dms", r.Latency.Avg)
+	fmt.Fprintf(&b, "\t🐢 %.1fms ±%.1fms", r.Latency.Avg, r.Latency.StdDev)
+
+	if r.Latency.Loss > 0 {
+		fmt.Fprintf(&b, " 📉 %.0f%%", r.Latency.Loss)
+	}
 
 	return b.String()
 
@@ -2,10 +2,12 @@ package aws
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"os"
 	"slices"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -14,14 +16,23 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/pkg/errors"
 	"github.com/yawn/instagpu/detect"
+	"github.com/yawn/instagpu/provider"
 )
 
 const NAME = "aws"
 
 type AWS struct {
 	cfg                aws.Config
-	instanceProfileARN string // populated by setup
+	instanceProfileARN string            // populated by setup
+	mutex              sync.Mutex        // guards zones
+	now                func() time.Time  // replaced in tests
+	zones              map[string]*zones // zone ids by region
 }
+
+// zones are the ids of the availability zones of a region by name, looked up once
+type zones struct {
+	ids  map[string]string
+	once sync.Once
 }
 
 func DefaultConfig(ctx context.Context) (aws.Config, error) {
@@ -58,6 +69,7 @@ func New(ctx context.Context) (*AWS, error) {
 func NewWithConfig(cfg aws.Config) *AWS {
 	return &AWS{
 		cfg: cfg,
+		now: time.Now,
 	}
 }
 
@@ -70,11 +82,14 @@ func (a *AWS) clientForRegion(region *detect.Region) *ec2.Client {
 
 }
 
-func (a *AWS) Instances(ctx context.Context, region *detect.Region) ([]*detect.Instance, error) {
+func (a *AWS) Instances(ctx context.Context, region *detect.Region) ([]*detect.Instance, []*provider.Skipped, error) {
 
 	client := a.clientForRegion(region)
 
-	var instances []*detect.Instance
+	var (
+		instances []*detect.Instance
+		skipped   []*provider.Skipped
+	)
 
 	paginator := ec2.NewDescribeInstanceTypesPaginator(client, &ec2.DescribeInstanceTypesInput{
 		Filters: []types.Filter{
@@ -87,8 +102,11 @@ func (a *AWS) Instances(ctx context.Context, region *detect.Region) ([]*detect.I
 			{
 				Name: aws.String("instance-type"),
 				Values: []string{
+					"dl*",
 					"g*",
+					"inf*",
 					"p*",
+					"trn*",
 				},
 			},
 			{
@@ -105,53 +123,140 @@ func (a *AWS) Instances(ctx context.Context, region *detect.Region) ([]*detect.I
 		res, err := paginator.NextPage(ctx)
 
 		if err != nil {
-			return nil, errors.Wrapf(err, "failed to enumerate candidate instances")
+			return nil, nil, errors.Wrapf(classify(err), "failed to enumerate candidate instances")
 		}
 
 		for _, e := range res.InstanceTypes {
 
-			if len(e.ProcessorInfo.SupportedArchitectures) != 1 {
-				panic("unexpected length of ProcessorInfo.SupportedArchitectures")
-			}
+			instance, reason := model(e, region)
 
-			if e.GpuInfo == nil {
-				panic("unexpected empty GpuInfo")
-			}
+			if instance == nil {
 
-			if len(e.GpuInfo.Gpus) != 1 {
-				panic("unexpected length of GpuInfo.Gpus")
-			}
+				skipped = append(skipped, &provider.Skipped{
+					Instance: string(e.InstanceType),
+					Reason:   reason,
+				})
 
-			if len(e.NetworkInfo.NetworkCards) == 0 {
-				panic("unexpected length of NetworkInfo.NetworkCards")
-			}
+				continue
 
-			instance := &detect.Instance{
-				Arch:       string(e.ProcessorInfo.SupportedArchitectures[0]),
-				Count:      uint(*e.VCpuInfo.DefaultCores),
-				ClockSpeed: float64(*e.ProcessorInfo.SustainedClockSpeedInGhz),
-				Memory:     uint64(*e.MemoryInfo.SizeInMiB),
-				Name:       string(e.InstanceType),
-				Network:    float64(*e.NetworkInfo.NetworkCards[0].PeakBandwidthInGbps),
-				Region:     region,
-				Vendor:     *e.ProcessorInfo.Manufacturer,
 			}
 
-			instance.GPU.Memory = uint64(*e.GpuInfo.TotalGpuMemoryInMiB)
+			instances = append(instances, instance)
+
+		}
+
+	}
 
-			gpus := e.GpuInfo.Gpus[0]
+	return instances, skipped, nil
 
-			instance.GPU.Count = uint(*gpus.Count)
-			instance.GPU.Name = *gpus.Name
-			instance.GPU.Vendor = *gpus.Manufacturer
+}
 
-			instances = append(instances, instance)
+// model converts an instance type, returning the reason if it cannot be modelled
+func model(e types.InstanceTypeInfo, region *detect.Region) (*detect.Instance, string) {
+
+	var archs []types.ArchitectureType
+
+	if e.ProcessorInfo != nil {
+
+		archs = slices.DeleteFunc(slices.Clone(e.ProcessorInfo.SupportedArchitectures), func(arch types.ArchitectureType) bool {
+			return arch == types.ArchitectureTypeI386 // always accompanied by x86_64
+		})
+
+	}
+
+	if len(archs) != 1 {
+		return nil, fmt.Sprintf("unsupported architectures %v", archs)
+	}
+
+	gpu, reason := accelerator(e)
+
+	if gpu == nil {
+		return nil, reason
+	}
+
+	instance := &detect.Instance{
+		Arch:       string(archs[0]),
+		ClockSpeed: aws.ToFloat64(e.ProcessorInfo.SustainedClockSpeedInGhz),
+		Name:       string(e.InstanceType),
+		Region:     region,
+		Vendor:     aws.ToString(e.ProcessorInfo.Manufacturer),
+	}
+
+	if e.VCpuInfo != nil {
+		instance.Count = uint(aws.ToInt32(e.VCpuInfo.DefaultCores))
+	}
+
+	if e.MemoryInfo != nil {
+		instance.Memory = uint64(aws.ToInt64(e.MemoryInfo.SizeInMiB))
+	}
+
+	if e.NetworkInfo != nil && len(e.NetworkInfo.NetworkCards) > 0 {
+		instance.Network = aws.ToFloat64(e.NetworkInfo.NetworkCards[0].PeakBandwidthInGbps)
+	}
+
+	instance.GPU = gpu
+
+	return instance, ""
+
+}
+
+// accelerator converts GPU, neuron or inference accelerator information, in this order of preference,
+// returning the reason if none can be modelled
+func accelerator(e types.InstanceTypeInfo) (*detect.GPU, string) {
+
+	switch {
 
+	case e.GpuInfo != nil && len(e.GpuInfo.Gpus) > 0:
+
+		if len(e.GpuInfo.Gpus) != 1 {
+			return nil, fmt.Sprintf("%d different gpu kinds", len(e.GpuInfo.Gpus))
+		}
+
+		gpus := e.GpuInfo.Gpus[0]
+
+		return &detect.GPU{
+			Count:  uint(aws.ToInt32(gpus.Count)),
+			Kind:   detect.KindGPU,
+			Memory: uint64(aws.ToInt32(e.GpuInfo.TotalGpuMemoryInMiB)),
+			Name:   aws.ToString(gpus.Name),
+			Vendor: aws.ToString(gpus.Manufacturer),
+		}, ""
+
+	case e.NeuronInfo != nil && len(e.NeuronInfo.NeuronDevices) > 0:
+
+		if len(e.NeuronInfo.NeuronDevices) != 1 {
+			return nil, fmt.Sprintf("%d different neuron device kinds", len(e.NeuronInfo.NeuronDevices))
 		}
 
+		devices := e.NeuronInfo.NeuronDevices[0]
+
+		return &detect.GPU{
+			Count:  uint(aws.ToInt32(devices.Count)),
+			Kind:   detect.KindNeuron,
+			Memory: uint64(aws.ToInt32(e.NeuronInfo.TotalNeuronDeviceMemoryInMiB)),
+			Name:   aws.ToString(devices.Name),
+			Vendor: "AWS", // neuron devices are always built by AWS
+		}, ""
+
+	case e.InferenceAcceleratorInfo != nil && len(e.InferenceAcceleratorInfo.Accelerators) > 0:
+
+		if len(e.InferenceAcceleratorInfo.Accelerators) != 1 {
+			return nil, fmt.Sprintf("%d different inference accelerator kinds", len(e.InferenceAcceleratorInfo.Accelerators))
+		}
+
+		accelerators := e.InferenceAcceleratorInfo.Accelerators[0]
+
+		return &detect.GPU{
+			Count:  uint(aws.ToInt32(accelerators.Count)),
+			Kind:   detect.KindInference,
+			Memory: uint64(aws.ToInt32(e.InferenceAcceleratorInfo.TotalInferenceMemoryInMiB)),
+			Name:   aws.ToString(accelerators.Name),
+			Vendor: aws.ToString(accelerators.Manufacturer),
+		}, ""
+
 	}
 
-	return instances, nil
+	return nil, "no accelerator information"
 
 }
 
@@ -178,7 +283,7 @@ func (a *AWS) Regions(ctx context.Context) ([]*detect.Region, error) {
 	})
 
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to enumerate regions")
+		return nil, errors.Wrapf(classify(err), "failed to enumerate regions")
 	}
 
 	for _, region := range res.Regions {
@@ -195,26 +300,22 @@ func (a *AWS) Regions(ctx context.Context) ([]*detect.Region, error) {
 
 }
 
-func (a *AWS) Prices(ctx context.Context, region *detect.Region, instance *detect.Instance) (*detect.Prices, error) {
-
-	// looking back a week
-	const WINDOW = -24 * 7
+func (a *AWS) Prices(ctx context.Context, region *detect.Region, instance *detect.Instance, window time.Duration) (*detect.Prices, error) {
 
 	client := a.clientForRegion(region)
 
 	var (
-		azs    = make(map[string]any)
-		prices []float64
+		end     = a.now()
+		history []detect.SpotPrice
+		start   = end.Add(-window)
 	)
 
-	window := time.Now().Add(time.Duration(WINDOW) * time.Hour)
-
 	paginator := ec2.NewDescribeSpotPriceHistoryPaginator(client, &ec2.DescribeSpotPriceHistoryInput{
 		InstanceTypes: []types.InstanceType{
 			types.InstanceType(instance.Name),
 		},
 		ProductDescriptions: []string{"Linux/UNIX"},
-		StartTime:           &window,
+		StartTime:           &start,
 	})
 
 	for paginator.HasMorePages() {
@@ -222,49 +323,85 @@ func (a *AWS) Prices(ctx context.Context, region *detect.Region, instance *detec
 		res, err := paginator.NextPage(ctx)
 
 		if err != nil {
-			return nil, errors.Wrapf(err, "failed to enumerate instances prices")
+			return nil, errors.Wrapf(classify(err), "failed to enumerate instances prices")
 		}
 
 		for _, e := range res.SpotPriceHistory {
 
-			azs[*e.AvailabilityZone] = struct{}{}
-
 			p, err := strconv.ParseFloat(*e.SpotPrice, 64)
 
 			if err != nil {
 				return nil, errors.Wrapf(err, "failed to parse price %q", *e.SpotPrice)
 			}
 
-			prices = append(prices, p)
+			history = append(history, detect.SpotPrice{
+				AvailabilityZone: *e.AvailabilityZone,
+				Price:            p,
+				Time:             *e.Timestamp,
+			})
 
 		}
 
 	}
 
-	if len(prices) > 0 {
+	prices := detect.NewPrices(instance, history, start, end)
 
-		price := &detect.Prices{
-			AvailablityZones: uint(len(azs)),
-			Instance:         instance,
-		}
+	if prices == nil {
+		return nil, nil
+	}
 
-		var avg float64
+	ids := a.zoneIDs(ctx, client, region)
 
-		for _, price := range prices {
-			avg += price
-		}
+	for _, zone := range prices.Zones {
+		zone.ID = ids[zone.Name]
+	}
+
+	return prices, nil
 
-		price.Avg = avg / float64(len(prices))
+}
 
-		slices.Sort(prices)
+// zoneIDs returns the ids of the availability zones of region by name, which unlike names are stable across
+// accounts - they are looked up once per region and, being informational, are empty if that fails
+func (a *AWS) zoneIDs(ctx context.Context, client *ec2.Client, region *detect.Region) map[string]string {
 
-		price.Min = prices[0]
-		price.Max = prices[len(prices)-1]
+	a.mutex.Lock()
+
+	if a.zones == nil {
+		a.zones = make(map[string]*zones)
+	}
 
-		return price, nil
+	z, ok := a.zones[region.Name]
 
+	if !ok {
+		z = new(zones)
+		a.zones[region.Name] = z
 	}
 
-	return nil, nil
+	a.mutex.Unlock()
+
+	z.once.Do(func() {
+
+		res, err := client.DescribeAvailabilityZones(ctx, &ec2.DescribeAvailabilityZonesInput{})
+
+		if err != nil {
+
+			slog.Warn("failed to enumerate availability zones, their ids are unknown",
+				slog.String("region", region.Name),
+				slog.String("error", err.Error()),
+			)
+
+			return
+
+		}
+
+		z.ids = make(map[string]string)
+
+		for _, zone := range res.AvailabilityZones {
+			z.ids[aws.ToString(zone.ZoneName)] = aws.ToString(zone.ZoneId)
+		}
+
+	})
+
+	return z.ids
 
 }
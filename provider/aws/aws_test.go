@@ -0,0 +1,456 @@
+package aws
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yawn/instagpu/detect"
+	"github.com/yawn/instagpu/provider"
+	"github.com/yawn/instagpu/provider/contract"
+)
+
+// standIn serves recorded EC2 responses from testdata/<scenario>, named after the action, the requested
+// instance type and the pagination token, e.g. DescribeSpotPriceHistory.g5.xlarge.page2.xml
+type standIn struct {
+	*httptest.Server
+	mutex    sync.Mutex
+	requests []string
+}
+
+func newStandIn(t *testing.T, scenario string) (*standIn, *AWS) {
+
+	s := new(standIn)
+
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		name := r.Form.Get("Action")
+
+		if instance := r.Form.Get("InstanceType.1"); instance != "" {
+			name += "." + instance
+		}
+
+		if token := r.Form.Get("NextToken"); token != "" {
+			name += "." + token
+		}
+
+		s.mutex.Lock()
+		s.requests = append(s.requests, name)
+		s.mutex.Unlock()
+
+		body, err := os.ReadFile(filepath.Join("testdata", scenario, name+".xml"))
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/xml")
+		w.Write(body)
+
+	}))
+
+	t.Cleanup(s.Close)
+
+	a := NewWithConfig(aws.Config{
+		BaseEndpoint:     aws.String(s.URL),
+		Credentials:      aws.AnonymousCredentials{},
+		Region:           "eu-west-1",
+		RetryMaxAttempts: 1,
+	})
+
+	a.now = func() time.Time {
+		return time.Date(2024, 9, 24, 0, 0, 0, 0, time.UTC) // the day after the recordings
+	}
+
+	return s, a
+
+}
+
+func TestRecorded(t *testing.T) {
+
+	var (
+		ctx     = context.Background()
+		s, a    = newStandIn(t, "recorded")
+		region  *detect.Region
+		indexed = make(map[string]*detect.Instance)
+	)
+
+	t.Run("regions", func(t *testing.T) {
+
+		assert := assert.New(t)
+
+		regions, err := a.Regions(ctx)
+
+		require.NoError(t, err)
+		require.Len(t, regions, 2)
+
+		region = regions[0]
+
+		assert.Equal("eu-west-1", region.Name)
+		assert.Equal("ec2.eu-west-1.amazonaws.com", region.Endpoint)
+		assert.Equal(NAME, region.Provider)
+
+	})
+
+	t.Run("instances", func(t *testing.T) {
+
+		assert := assert.New(t)
+
+		instances, skipped, err := a.Instances(ctx, region)
+
+		require.NoError(t, err)
+		require.Len(t, instances, 3, "instances across both pages")
+		assert.Empty(skipped)
+
+		for _, instance := range instances {
+			indexed[instance.Name] = instance
+		}
+
+		g5 := indexed["g5.xlarge"]
+
+		require.NotNil(t, g5)
+
+		assert.Equal("x86_64", g5.Arch)
+		assert.EqualValues(2, g5.Count)
+		assert.Equal(3.3, g5.ClockSpeed)
+		assert.EqualValues(16384, g5.Memory)
+		assert.Equal(10.0, g5.Network)
+		assert.Same(region, g5.Region)
+		assert.Equal("AMD", g5.Vendor)
+		assert.EqualValues(1, g5.GPU.Count)
+		assert.EqualValues(24576, g5.GPU.Memory)
+		assert.Equal("A10G", g5.GPU.Name)
+		assert.Equal("NVIDIA", g5.GPU.Vendor)
+
+	})
+
+	t.Run("prices", func(t *testing.T) {
+
+		assert := assert.New(t)
+
+		prices, err := a.Prices(ctx, region, indexed["g5.xlarge"], contract.WINDOW)
+
+		require.NoError(t, err)
+		require.NotNil(t, prices)
+
+		// over the last day, eu-west-1a held 0.39 for 18h (carried over from before) and 0.45 for 6h, while
+		// eu-west-1b held 0.42 throughout
+		assert.EqualValues(2, prices.AvailabilityZones)
+		assert.InDelta(0.4125, prices.Avg, 1e-9, "time-weighted across both pages")
+		assert.InDelta(0.435, prices.Current, 1e-9)
+		assert.Equal(0.39, prices.Min)
+		assert.Equal(0.45, prices.Max)
+		assert.Equal(0.42, prices.Median)
+		assert.Equal(0.45, prices.P90)
+		assert.InDelta(0.0198, prices.StdDev, 1e-4)
+		assert.InDelta(0.03375, prices.Trend, 1e-9, "rising")
+
+		if assert.Len(prices.Zones, 2) {
+
+			a, b := prices.Zones[0], prices.Zones[1]
+
+			assert.Equal("eu-west-1a", a.Name)
+			assert.Equal("euw1-az3", a.ID)
+			assert.Len(a.History, 2)
+			assert.InDelta(0.405, a.Avg, 1e-9)
+			assert.Equal(0.45, a.Current)
+			assert.Equal("eu-west-1b", b.Name)
+			assert.Equal("euw1-az1", b.ID)
+			assert.Equal(0.42, b.Avg)
+			assert.Same(a, prices.Cheapest(), "on average, though currently pricier")
+
+		}
+		assert.Same(indexed["g5.xlarge"], prices.Instance)
+
+		prices, err = a.Prices(ctx, region, indexed["p3.2xlarge"], contract.WINDOW)
+
+		assert.NoError(err)
+		assert.Nil(prices, "not available as spot")
+
+	})
+
+	t.Run("pagination", func(t *testing.T) {
+		assert.Subset(t, s.requests, []string{
+			"DescribeInstanceTypes",
+			"DescribeInstanceTypes.page2",
+			"DescribeAvailabilityZones",
+			"DescribeSpotPriceHistory.g5.xlarge",
+			"DescribeSpotPriceHistory.g5.xlarge.page2",
+		})
+	})
+
+	t.Run("contract", func(t *testing.T) {
+		contract.Discovery(t, a)
+	})
+
+}
+
+func TestMalformed(t *testing.T) {
+
+	var (
+		ctx  = context.Background()
+		_, a = newStandIn(t, "malformed")
+	)
+
+	regions, err := a.Regions(ctx)
+
+	require.NoError(t, err)
+
+	t.Run("instances", func(t *testing.T) {
+
+		_, _, err := a.Instances(ctx, regions[0])
+
+		assert.ErrorContains(t, err, "failed to enumerate candidate instances")
+
+	})
+
+	t.Run("prices", func(t *testing.T) {
+
+		instance := &detect.Instance{
+			GPU:    &detect.GPU{},
+			Name:   "g5.xlarge",
+			Region: regions[0],
+		}
+
+		_, err := a.Prices(ctx, regions[0], instance, contract.WINDOW)
+
+		assert.ErrorContains(t, err, `failed to parse price "n/a"`)
+
+	})
+
+	t.Run("missing", func(t *testing.T) {
+
+		instance := &detect.Instance{
+			GPU:    &detect.GPU{},
+			Name:   "g6.xlarge",
+			Region: regions[0],
+		}
+
+		_, err := a.Prices(ctx, regions[0], instance, contract.WINDOW)
+
+		assert.ErrorContains(t, err, "failed to enumerate instances prices")
+
+	})
+
+}
+
+func TestUnusual(t *testing.T) {
+
+	assert := assert.New(t)
+
+	var (
+		ctx  = context.Background()
+		_, a = newStandIn(t, "unusual")
+	)
+
+	regions, err := a.Regions(ctx)
+
+	require.NoError(t, err)
+
+	instances, skipped, err := a.Instances(ctx, regions[0])
+
+	require.NoError(t, err)
+	require.Len(t, instances, 5)
+
+	metal := instances[0]
+
+	assert.Equal("g4dn.metal", metal.Name)
+	assert.Equal("x86_64", metal.Arch, "i386 is ignored")
+	assert.Zero(metal.ClockSpeed, "unknown clock speed")
+	assert.Zero(metal.Network, "no network cards")
+	assert.EqualValues(8, metal.GPU.Count)
+
+	assert.Equal("g5g.xlarge", instances[1].Name)
+	assert.Equal("arm64", instances[1].Arch)
+
+	assert.Equal(&detect.GPU{
+		Count:  8,
+		Kind:   detect.KindGPU,
+		Memory: 262144,
+		Name:   "Gaudi HL-205",
+		Vendor: "Habana",
+	}, instances[2].GPU)
+
+	assert.Equal(&detect.GPU{
+		Count:  1,
+		Kind:   detect.KindInference,
+		Memory: 8192,
+		Name:   "Inferentia",
+		Vendor: "AWS",
+	}, instances[3].GPU)
+
+	assert.Equal(&detect.GPU{
+		Count:  1,
+		Kind:   detect.KindNeuron,
+		Memory: 32768,
+		Name:   "Trainium",
+		Vendor: "AWS",
+	}, instances[4].GPU)
+
+	assert.Equal([]*provider.Skipped{
+		{Instance: "p9.mixed", Reason: "2 different gpu kinds"},
+		{Instance: "p9.multiarch", Reason: "unsupported architectures [arm64 x86_64]"},
+		{Instance: "g9.nogpu", Reason: "no accelerator information"},
+	}, skipped)
+
+}
+
+// TestCatalog checks that the catalog knows every accelerator of the recorded instances, short of the ones
+// lacking published performance figures
+func TestCatalog(t *testing.T) {
+
+	var (
+		catalog     = detect.CurrentCatalog()
+		unpublished = []string{"Habana-Gaudi HL-205"}
+	)
+
+	for _, scenario := range []string{"recorded", "unusual"} {
+
+		t.Run(scenario, func(t *testing.T) {
+
+			ctx := context.Background()
+
+			_, a := newStandIn(t, scenario)
+
+			regions, err := a.Regions(ctx)
+
+			require.NoError(t, err)
+
+			instances, _, err := a.Instances(ctx, regions[0])
+
+			require.NoError(t, err)
+			require.NotEmpty(t, instances)
+
+			for _, instance := range instances {
+
+				tag := instance.GPU.Tag()
+
+				if slices.Contains(unpublished, tag) {
+					continue
+				}
+
+				_, ok := catalog[tag]
+
+				assert.True(t, ok, "%s of %s lacks a catalog entry", tag, instance.Name)
+
+			}
+
+		})
+
+	}
+
+}
+
+func TestMachines(t *testing.T) {
+
+	var (
+		ctx    = context.Background()
+		region = &detect.Region{Name: "eu-west-1", Provider: NAME}
+	)
+
+	prices := func(machines []*detect.Machine) map[string]float64 {
+
+		prices := make(map[string]float64)
+
+		for _, machine := range machines {
+			prices[machine.ID] = machine.Price
+		}
+
+		return prices
+
+	}
+
+	t.Run("priced", func(t *testing.T) {
+
+		s, a := newStandIn(t, "machines")
+
+		machines, err := a.Machines(ctx, region)
+
+		require.NoError(t, err)
+		assert.Equal(t, map[string]float64{
+			"i-0aaaaaaaaaaaaaaa1": 0.4, // latest one
+			"i-0aaaaaaaaaaaaaaa2": 0.45,
+			"i-0aaaaaaaaaaaaaaa3": 0, // no current price
+		}, prices(machines))
+		assert.Equal(t, []string{"DescribeInstances", "DescribeSpotPriceHistory.g5.xlarge"}, s.requests, "prices looked up at once")
+
+	})
+
+	t.Run("unpriced", func(t *testing.T) {
+
+		assert := assert.New(t)
+
+		_, a := newStandIn(t, "unpriced")
+
+		machines, err := a.Machines(ctx, region)
+
+		require.NoError(t, err, "prices are informational")
+		assert.Len(machines, 3)
+		assert.Equal(map[string]float64{"i-0aaaaaaaaaaaaaaa1": 0, "i-0aaaaaaaaaaaaaaa2": 0, "i-0aaaaaaaaaaaaaaa3": 0}, prices(machines))
+
+		machines, err = a.machines(ctx, region)
+
+		require.NoError(t, err)
+		assert.Len(machines, 3, "enumerated for terminating and tearing down without any price lookups")
+
+	})
+
+}
+
+func TestZoneless(t *testing.T) {
+
+	var (
+		ctx      = context.Background()
+		s, a     = newStandIn(t, "zoneless")
+		region   = &detect.Region{Name: "eu-west-1", Provider: NAME}
+		instance = &detect.Instance{
+			GPU:    &detect.GPU{},
+			Name:   "g4dn.xlarge",
+			Region: region,
+		}
+		wg sync.WaitGroup
+	)
+
+	for range 4 {
+
+		wg.Add(1)
+
+		go func() {
+
+			defer wg.Done()
+
+			prices, err := a.Prices(ctx, region, instance, contract.WINDOW)
+
+			if assert.NoError(t, err, "zone ids are informational") && assert.Len(t, prices.Zones, 1) {
+				assert.Equal(t, "eu-west-1c", prices.Zones[0].Name)
+				assert.Empty(t, prices.Zones[0].ID)
+			}
+
+		}()
+
+	}
+
+	wg.Wait()
+
+	zones := slices.DeleteFunc(s.requests, func(name string) bool {
+		return name != "DescribeAvailabilityZones"
+	})
+
+	assert.Len(t, zones, 1, "looked up once per region")
+
+}
@@ -0,0 +1,29 @@
+package aws
+
+import (
+	"slices"
+
+	"github.com/aws/smithy-go"
+	"github.com/pkg/errors"
+	"github.com/yawn/instagpu/provider"
+)
+
+// throttling lists error codes signalling rate limiting
+var throttling = []string{
+	"RequestLimitExceeded",
+	"Throttling",
+	"ThrottlingException",
+}
+
+// classify marks throttling errors as provider.ErrThrottled
+func classify(err error) error {
+
+	var apiError smithy.APIError
+
+	if errors.As(err, &apiError) && slices.Contains(throttling, apiError.ErrorCode()) {
+		return provider.Throttled(err)
+	}
+
+	return err
+
+}
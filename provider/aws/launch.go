@@ -0,0 +1,173 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/pkg/errors"
+	"github.com/yawn/instagpu/detect"
+	"github.com/yawn/instagpu/provider"
+)
+
+// IMAGE is the name pattern of the default machine image, resolved per architecture
+const IMAGE = "Deep Learning Base OSS Nvidia Driver GPU AMI (Ubuntu 22.04) *"
+
+func (a *AWS) image(ctx context.Context, client *ec2.Client, instance *detect.Instance) (string, error) {
+
+	res, err := client.DescribeImages(ctx, &ec2.DescribeImagesInput{
+		Filters: []types.Filter{
+			{
+				Name:   aws.String("architecture"),
+				Values: []string{instance.Arch},
+			},
+			{
+				Name:   aws.String("name"),
+				Values: []string{IMAGE},
+			},
+			{
+				Name:   aws.String("state"),
+				Values: []string{"available"},
+			},
+		},
+		Owners: []string{"amazon"},
+	})
+
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to enumerate images")
+	}
+
+	if len(res.Images) == 0 {
+		return "", fmt.Errorf("no default image for architecture %q in region %q", instance.Arch, instance.Region.Name)
+	}
+
+	latest := slices.MaxFunc(res.Images, func(a, b types.Image) int {
+		return strings.Compare(aws.ToString(a.CreationDate), aws.ToString(b.CreationDate))
+	})
+
+	slog.Debug("default image identified",
+		slog.String("image", *latest.ImageId),
+		slog.String("name", aws.ToString(latest.Name)),
+	)
+
+	return *latest.ImageId, nil
+
+}
+
+func (a *AWS) Launch(ctx context.Context, prices *detect.Prices, options *provider.LaunchOptions) (*detect.Machine, error) {
+
+	var (
+		instance = prices.Instance
+		client   = a.clientForRegion(instance.Region)
+		image    = options.Image
+		maxPrice = options.MaxPrice
+	)
+
+	profile, err := a.instanceProfile(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if image == "" {
+
+		image, err = a.image(ctx, client, instance)
+
+		if err != nil {
+			return nil, err
+		}
+
+	}
+
+	if maxPrice == 0 {
+		maxPrice = prices.Max
+	}
+
+	req := &ec2.RunInstancesInput{
+		IamInstanceProfile: &types.IamInstanceProfileSpecification{
+			Arn: &profile,
+		},
+		ImageId: &image,
+		InstanceMarketOptions: &types.InstanceMarketOptionsRequest{
+			MarketType: types.MarketTypeSpot,
+			SpotOptions: &types.SpotMarketOptions{
+				InstanceInterruptionBehavior: types.InstanceInterruptionBehaviorTerminate,
+				MaxPrice:                     aws.String(strconv.FormatFloat(maxPrice, 'f', 6, 64)),
+				SpotInstanceType:             types.SpotInstanceTypeOneTime,
+			},
+		},
+		InstanceType: types.InstanceType(instance.Name),
+		MaxCount:     aws.Int32(1),
+		MinCount:     aws.Int32(1),
+		TagSpecifications: []types.TagSpecification{
+			{
+				ResourceType: types.ResourceTypeInstance,
+				Tags:         tags.ToEC2(),
+			},
+			{
+				ResourceType: types.ResourceTypeSpotInstancesRequest,
+				Tags:         tags.ToEC2(),
+			},
+		},
+	}
+
+	if options.Key != "" {
+		req.KeyName = &options.Key
+	}
+
+	if options.Zone != "" {
+		req.Placement = &types.Placement{
+			AvailabilityZone: &options.Zone,
+		}
+	}
+
+	slog.Debug("launching instance",
+		slog.String("image", image),
+		slog.String("instance", instance.Name),
+		slog.Float64("max_price", maxPrice),
+		slog.String("region", instance.Region.Name),
+		slog.String("zone", options.Zone),
+	)
+
+	res, err := client.RunInstances(ctx, req)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to launch instance %q in region %q", instance.Name, instance.Region.Name)
+	}
+
+	e := res.Instances[0]
+
+	machine := &detect.Machine{
+		ID:       *e.InstanceId,
+		Instance: instance.Name,
+		Launched: aws.ToTime(e.LaunchTime),
+		MaxPrice: maxPrice,
+		Price:    prices.Current,
+		Region:   instance.Region,
+	}
+
+	if e.State != nil {
+		machine.State = string(e.State.Name)
+	}
+
+	if e.Placement != nil {
+		machine.Zone = aws.ToString(e.Placement.AvailabilityZone)
+	}
+
+	for _, zone := range prices.Zones {
+
+		if zone.Name == machine.Zone {
+			machine.Price = zone.Current // of the zone actually launched in, if known
+		}
+
+	}
+
+	return machine, nil
+
+}
@@ -0,0 +1,216 @@
+package aws
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/pkg/errors"
+	"github.com/yawn/instagpu/detect"
+)
+
+func (a *AWS) Machines(ctx context.Context, region *detect.Region) ([]*detect.Machine, error) {
+
+	machines, err := a.machines(ctx, region)
+
+	if err != nil {
+		return nil, err
+	}
+
+	a.currentPrices(ctx, a.clientForRegion(region), region, machines)
+
+	return machines, nil
+
+}
+
+// machines enumerates all tagged, non-terminated instances in a region matching the optional filters, without
+// prices so terminating and tearing down never depend on them
+func (a *AWS) machines(ctx context.Context, region *detect.Region, extra ...types.Filter) ([]*detect.Machine, error) {
+
+	client := a.clientForRegion(region)
+
+	var machines []*detect.Machine
+
+	filters := append(tags.ToEC2Filters(), extra...)
+
+	filters = append(filters, types.Filter{
+		Name: aws.String("instance-state-name"),
+		Values: []string{
+			string(types.InstanceStateNamePending),
+			string(types.InstanceStateNameRunning),
+			string(types.InstanceStateNameShuttingDown),
+			string(types.InstanceStateNameStopping),
+			string(types.InstanceStateNameStopped),
+		},
+	})
+
+	paginator := ec2.NewDescribeInstancesPaginator(client, &ec2.DescribeInstancesInput{
+		Filters: filters,
+	})
+
+	for paginator.HasMorePages() {
+
+		res, err := paginator.NextPage(ctx)
+
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to enumerate instances")
+		}
+
+		for _, reservation := range res.Reservations {
+
+			for _, e := range reservation.Instances {
+
+				machine := &detect.Machine{
+					ID:       *e.InstanceId,
+					Instance: string(e.InstanceType),
+					Launched: aws.ToTime(e.LaunchTime),
+					Region:   region,
+				}
+
+				if e.State != nil {
+					machine.State = string(e.State.Name)
+				}
+
+				if e.Placement != nil {
+					machine.Zone = aws.ToString(e.Placement.AvailabilityZone)
+				}
+
+				machines = append(machines, machine)
+
+			}
+
+		}
+
+	}
+
+	return machines, nil
+
+}
+
+// currentPrices sets the latest spot prices of machines, looked up at once for all their instance types and
+// availability zones - prices being informational, failing to look them up only warns
+func (a *AWS) currentPrices(ctx context.Context, client *ec2.Client, region *detect.Region, machines []*detect.Machine) {
+
+	if len(machines) == 0 {
+		return
+	}
+
+	var (
+		instances []types.InstanceType
+		latest    = make(map[string]types.SpotPrice)
+		now       = a.now()
+		zones     []string
+	)
+
+	for _, machine := range machines {
+
+		if instance := types.InstanceType(machine.Instance); !slices.Contains(instances, instance) {
+			instances = append(instances, instance)
+		}
+
+		if !slices.Contains(zones, machine.Zone) {
+			zones = append(zones, machine.Zone)
+		}
+
+	}
+
+	slices.Sort(instances)
+	slices.Sort(zones)
+
+	paginator := ec2.NewDescribeSpotPriceHistoryPaginator(client, &ec2.DescribeSpotPriceHistoryInput{
+		EndTime: &now,
+		Filters: []types.Filter{
+			{
+				Name:   aws.String("availability-zone"),
+				Values: zones,
+			},
+		},
+		InstanceTypes:       instances,
+		ProductDescriptions: []string{"Linux/UNIX"},
+		StartTime:           &now,
+	})
+
+	for paginator.HasMorePages() {
+
+		res, err := paginator.NextPage(ctx)
+
+		if err != nil {
+
+			slog.Warn("failed to retrieve current prices of machines",
+				slog.String("region", region.Name),
+				slog.String("error", err.Error()),
+			)
+
+			return
+
+		}
+
+		for _, e := range res.SpotPriceHistory {
+
+			key := string(e.InstanceType) + "/" + aws.ToString(e.AvailabilityZone)
+
+			if known, ok := latest[key]; !ok || aws.ToTime(e.Timestamp).After(aws.ToTime(known.Timestamp)) {
+				latest[key] = e
+			}
+
+		}
+
+	}
+
+	for _, machine := range machines {
+
+		e, ok := latest[machine.Instance+"/"+machine.Zone]
+
+		if !ok {
+
+			slog.Warn("no current price for machine",
+				slog.String("machine", machine.ID),
+			)
+
+			continue
+
+		}
+
+		price, err := strconv.ParseFloat(aws.ToString(e.SpotPrice), 64)
+
+		if err != nil {
+
+			slog.Warn("failed to parse current price of machine",
+				slog.String("machine", machine.ID),
+				slog.String("price", aws.ToString(e.SpotPrice)),
+			)
+
+			continue
+
+		}
+
+		machine.Price = price
+
+	}
+
+}
+
+func (a *AWS) Terminate(ctx context.Context, machine *detect.Machine) error {
+
+	client := a.clientForRegion(machine.Region)
+
+	slog.Debug("terminating instance",
+		slog.String("machine", machine.ID),
+		slog.String("region", machine.Region.Name),
+	)
+
+	_, err := client.TerminateInstances(ctx, &ec2.TerminateInstancesInput{
+		InstanceIds: []string{machine.ID},
+	})
+
+	if err != nil {
+		return errors.Wrapf(err, "failed to terminate machine %q", machine.ID)
+	}
+
+	return nil
+
+}
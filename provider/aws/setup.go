@@ -5,14 +5,23 @@ import (
 	_ "embed"
 	"fmt"
 	"log/slog"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
 	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/aws/smithy-go"
 	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
 )
 
+// STACK is the name of the cloudformation stack holding the foundational infrastructure
+const STACK = "InstaGPUv1"
+
 //go:embed cloudformation.yml
 var stack string
 
@@ -21,16 +30,13 @@ func (a *AWS) Setup(ctx context.Context) error {
 	var (
 		client      = cloudformation.NewFromConfig(a.cfg)
 		deadline, _ = ctx.Deadline()
-		name        = "InstaGPUv1"
+		name        = STACK
 		op          = "create"
 		outputs     func(context.Context) (*cloudformation.DescribeStacksOutput, error)
 		req         = &cloudformation.DescribeStacksInput{
 			StackName: &name,
 		}
 		timeout = deadline.Sub(time.Now())
-		tags    = Tags{ // TODO: support custom tags
-			"InstaGPU": "v1",
-		}
 	)
 
 	_, err := client.DescribeStacks(ctx, &cloudformation.DescribeStacksInput{
@@ -127,3 +133,124 @@ func (a *AWS) Setup(ctx context.Context) error {
 	return nil
 
 }
+
+// instanceProfile returns the instance profile created by setup, looking it up in the stack outputs if required
+func (a *AWS) instanceProfile(ctx context.Context) (string, error) {
+
+	if a.instanceProfileARN != "" {
+		return a.instanceProfileARN, nil
+	}
+
+	var (
+		client = cloudformation.NewFromConfig(a.cfg)
+		name   = STACK
+	)
+
+	res, err := client.DescribeStacks(ctx, &cloudformation.DescribeStacksInput{
+		StackName: &name,
+	})
+
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to describe stack %q - please run setup first", name)
+	}
+
+	if len(res.Stacks) == 0 || len(res.Stacks[0].Outputs) == 0 {
+		return "", fmt.Errorf("stack %q has no outputs - please run setup first", name)
+	}
+
+	a.instanceProfileARN = *res.Stacks[0].Outputs[0].OutputValue
+
+	slog.Debug("instance profile identified",
+		slog.String("arn", a.instanceProfileARN),
+	)
+
+	return a.instanceProfileARN, nil
+
+}
+
+func (a *AWS) Teardown(ctx context.Context) error {
+
+	var (
+		client      = cloudformation.NewFromConfig(a.cfg)
+		deadline, _ = ctx.Deadline()
+		name        = STACK
+		req         = &cloudformation.DescribeStacksInput{
+			StackName: &name,
+		}
+		timeout = deadline.Sub(time.Now())
+	)
+
+	profile, err := a.instanceProfile(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	regions, err := a.Regions(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	var (
+		mutex  sync.Mutex
+		inUse  []string
+		wg, gc = errgroup.WithContext(ctx)
+	)
+
+	for _, region := range regions {
+
+		wg.Go(func() error {
+
+			machines, err := a.machines(gc, region, ec2types.Filter{
+				Name:   aws.String("iam-instance-profile.arn"),
+				Values: []string{profile},
+			})
+
+			if err != nil {
+				return err
+			}
+
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			for _, machine := range machines {
+				inUse = append(inUse, fmt.Sprintf("%s (%s)", machine.ID, region.Name))
+			}
+
+			return nil
+
+		})
+
+	}
+
+	if err := wg.Wait(); err != nil {
+		return err
+	}
+
+	if len(inUse) > 0 {
+		slices.Sort(inUse)
+		return fmt.Errorf("instance profile still in use by %s - please terminate first", strings.Join(inUse, ", "))
+	}
+
+	slog.Debug("deleting stack")
+
+	_, err = client.DeleteStack(ctx, &cloudformation.DeleteStackInput{
+		StackName: &name,
+	})
+
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete cloudformation stack")
+	}
+
+	waiter := cloudformation.NewStackDeleteCompleteWaiter(client)
+
+	if err := waiter.Wait(ctx, req, timeout); err != nil {
+		return errors.Wrapf(err, "failed to wait for stack deletion")
+	}
+
+	a.instanceProfileARN = ""
+
+	return nil
+
+}
@@ -1,12 +1,20 @@
 package aws
 
 import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
 	cf "github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
 	ec2 "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 )
 
 type Tags map[string]string
 
+// tags are attached to all resources created by instagpu
+var tags = Tags{ // TODO: support custom tags
+	"InstaGPU": "v1",
+}
+
 func (t Tags) ToCF() (tags []cf.Tag) {
 
 	for k, v := range t {
@@ -32,3 +40,16 @@ func (t Tags) ToEC2() (tags []ec2.Tag) {
 	return
 
 }
+
+func (t Tags) ToEC2Filters() (filters []ec2.Filter) {
+
+	for k, v := range t {
+		filters = append(filters, ec2.Filter{
+			Name:   aws.String(fmt.Sprintf("tag:%s", k)),
+			Values: []string{v},
+		})
+	}
+
+	return
+
+}
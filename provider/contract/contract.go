@@ -0,0 +1,157 @@
+// Package contract is a test suite every provider.Provider implementation must pass
+package contract
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yawn/instagpu/detect"
+	"github.com/yawn/instagpu/provider"
+)
+
+// WINDOW is the price look-back window used by the suite
+const WINDOW = 24 * time.Hour
+
+// Discovery verifies regions, instances and prices reported by p
+func Discovery(t *testing.T, p provider.Provider) {
+
+	ctx := context.Background()
+
+	require.NotEmpty(t, p.Name(), "provider name")
+
+	regions, err := p.Regions(ctx)
+
+	require.NoError(t, err)
+	require.NotEmpty(t, regions, "regions")
+
+	for _, region := range regions {
+
+		t.Run(region.Name, func(t *testing.T) {
+
+			assert := assert.New(t)
+
+			assert.NotEmpty(region.Name, "region name")
+			assert.Equal(p.Name(), region.Provider, "region provider")
+
+			instances, skipped, err := p.Instances(ctx, region)
+
+			require.NoError(t, err)
+
+			for _, skipped := range skipped {
+				assert.NotEmpty(skipped.Instance, "skipped instance name")
+				assert.NotEmpty(skipped.Reason, "reason for skipping %q", skipped.Instance)
+			}
+
+			for _, instance := range instances {
+
+				assert.NotEmpty(instance.Name, "instance name")
+				assert.Equal(region.Name, instance.Region.Name, "instance region of %q", instance.Name)
+				assert.NotNil(instance.GPU, "gpu of %q", instance.Name)
+
+				prices, err := p.Prices(ctx, region, instance, WINDOW)
+
+				require.NoError(t, err)
+
+				if prices == nil {
+					continue // not available as spot
+				}
+
+				assert.Same(instance, prices.Instance, "prices instance of %q", instance.Name)
+				assert.Positive(prices.AvailabilityZones, "availability zones of %q", instance.Name)
+				assert.LessOrEqual(prices.Min, prices.Avg, "minimum price of %q", instance.Name)
+				assert.LessOrEqual(prices.Avg, prices.Max, "maximum price of %q", instance.Name)
+
+			}
+
+		})
+
+	}
+
+}
+
+// Lifecycle verifies setup, launch, enumeration, termination and teardown against p, using the first
+// instance available as spot
+func Lifecycle(t *testing.T, p provider.Provider) {
+
+	ctx := context.Background()
+
+	candidate := candidate(t, p)
+
+	require.NotNil(t, candidate, "no instance available as spot")
+
+	require.NoError(t, p.Setup(ctx))
+
+	machine, err := p.Launch(ctx, candidate, &provider.LaunchOptions{})
+
+	require.NoError(t, err)
+	require.NotEmpty(t, machine.ID, "machine id")
+	assert.Equal(t, candidate.Instance.Name, machine.Instance, "machine instance")
+	assert.Equal(t, candidate.Max, machine.MaxPrice, "machine max price defaults to maximum price")
+
+	region := candidate.Instance.Region
+
+	assert.Contains(t, ids(t, p, region), machine.ID)
+
+	assert.Error(t, p.Teardown(ctx), "teardown must refuse while machines are running")
+
+	require.NoError(t, p.Terminate(ctx, machine))
+
+	assert.NotContains(t, ids(t, p, region), machine.ID)
+
+	require.NoError(t, p.Teardown(ctx))
+
+}
+
+func candidate(t *testing.T, p provider.Provider) *detect.Prices {
+
+	ctx := context.Background()
+
+	regions, err := p.Regions(ctx)
+
+	require.NoError(t, err)
+
+	for _, region := range regions {
+
+		instances, _, err := p.Instances(ctx, region)
+
+		require.NoError(t, err)
+
+		for _, instance := range instances {
+
+			prices, err := p.Prices(ctx, region, instance, WINDOW)
+
+			require.NoError(t, err)
+
+			if prices != nil {
+				return prices
+			}
+
+		}
+
+	}
+
+	return nil
+
+}
+
+func ids(t *testing.T, p provider.Provider, region *detect.Region) (ids []string) {
+
+	machines, err := p.Machines(context.Background(), region)
+
+	require.NoError(t, err)
+
+	for _, machine := range machines {
+
+		if !slices.Contains([]string{"shutting-down", "terminated"}, machine.State) {
+			ids = append(ids, machine.ID)
+		}
+
+	}
+
+	return
+
+}
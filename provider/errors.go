@@ -0,0 +1,29 @@
+package provider
+
+import (
+	"errors"
+)
+
+// ErrThrottled signals that a provider rate limited a request
+var ErrThrottled = errors.New("throttled by provider")
+
+type throttled struct {
+	err error
+}
+
+// Throttled marks err as caused by rate limiting, making it match ErrThrottled
+func Throttled(err error) error {
+	return &throttled{err: err}
+}
+
+func (t *throttled) Error() string {
+	return t.err.Error()
+}
+
+func (t *throttled) Is(target error) bool {
+	return target == ErrThrottled
+}
+
+func (t *throttled) Unwrap() error {
+	return t.err
+}
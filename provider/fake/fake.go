@@ -0,0 +1,335 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yawn/instagpu/detect"
+	"github.com/yawn/instagpu/provider"
+)
+
+const NAME = "fake"
+
+// Fake is an in-memory provider serving configured regions, instances and prices
+type Fake struct {
+	Delay     time.Duration // applied to every call
+	errors    map[string]error
+	instances map[string][]*detect.Instance
+	machines  []*detect.Machine
+	mutex     sync.Mutex
+	prices    map[string]*detect.Prices
+	regions   []*detect.Region
+	sequence  int
+	setup     bool
+	skipped   map[string][]*provider.Skipped
+}
+
+func New() *Fake {
+	return &Fake{
+		errors:    make(map[string]error),
+		instances: make(map[string][]*detect.Instance),
+		prices:    make(map[string]*detect.Prices),
+		skipped:   make(map[string][]*provider.Skipped),
+	}
+}
+
+// Key identifies a call for Fail, e.g. Key("prices", "eu-west-1", "g5.xlarge")
+func Key(call string, args ...string) string {
+	return strings.Join(append([]string{call}, args...), "/")
+}
+
+// AddRegion adds an empty region
+func (f *Fake) AddRegion(name string) *detect.Region {
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	region := &detect.Region{
+		Endpoint: fmt.Sprintf("%s.%s.invalid", name, NAME),
+		Name:     name,
+		Provider: NAME,
+	}
+
+	f.regions = append(f.regions, region)
+
+	return region
+
+}
+
+// AddInstance adds an instance to an existing region - nil prices model instances not available as spot
+func (f *Fake) AddInstance(region string, instance *detect.Instance, prices *detect.Prices) {
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	idx := slices.IndexFunc(f.regions, func(r *detect.Region) bool {
+		return r.Name == region
+	})
+
+	if idx == -1 {
+		panic(fmt.Sprintf("unknown region %q", region))
+	}
+
+	instance.Region = f.regions[idx]
+
+	if prices != nil {
+		prices.Instance = instance
+	}
+
+	f.instances[region] = append(f.instances[region], instance)
+	f.prices[Key("prices", region, instance.Name)] = prices
+
+}
+
+// AddSkipped adds an instance the fake reports as skipped
+func (f *Fake) AddSkipped(region, instance, reason string) {
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.skipped[region] = append(f.skipped[region], &provider.Skipped{
+		Instance: instance,
+		Reason:   reason,
+	})
+
+}
+
+// Fail makes the call identified by key return err
+func (f *Fake) Fail(key string, err error) {
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.errors[key] = err
+
+}
+
+// call simulates latency and returns a configured error for key, if any
+func (f *Fake) call(ctx context.Context, key string) error {
+
+	if f.Delay > 0 {
+
+		select {
+		case <-time.After(f.Delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.errors[key]
+
+}
+
+func (f *Fake) Instances(ctx context.Context, region *detect.Region) ([]*detect.Instance, []*provider.Skipped, error) {
+
+	if err := f.call(ctx, Key("instances", region.Name)); err != nil {
+		return nil, nil, err
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	var instances []*detect.Instance
+
+	for _, instance := range f.instances[region.Name] {
+
+		clone := *instance
+
+		if instance.GPU != nil {
+			gpu := *instance.GPU
+			clone.GPU = &gpu
+		}
+
+		instances = append(instances, &clone)
+
+	}
+
+	return instances, slices.Clone(f.skipped[region.Name]), nil
+
+}
+
+func (f *Fake) Launch(ctx context.Context, prices *detect.Prices, options *provider.LaunchOptions) (*detect.Machine, error) {
+
+	instance := prices.Instance
+
+	if err := f.call(ctx, Key("launch", instance.Region.Name, instance.Name)); err != nil {
+		return nil, err
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if !f.setup {
+		return nil, fmt.Errorf("provider %q not setup", NAME)
+	}
+
+	maxPrice := options.MaxPrice
+
+	if maxPrice == 0 {
+		maxPrice = prices.Max
+	}
+
+	zone := options.Zone
+
+	if zone == "" {
+		zone = fmt.Sprintf("%sa", instance.Region.Name)
+	}
+
+	f.sequence++
+
+	machine := &detect.Machine{
+		ID:       fmt.Sprintf("%s-%d", NAME, f.sequence),
+		Instance: instance.Name,
+		Launched: time.Now(),
+		MaxPrice: maxPrice,
+		Price:    prices.Avg,
+		Region:   instance.Region,
+		State:    "running",
+		Zone:     zone,
+	}
+
+	f.machines = append(f.machines, machine)
+
+	return machine, nil
+
+}
+
+func (f *Fake) Machines(ctx context.Context, region *detect.Region) ([]*detect.Machine, error) {
+
+	if err := f.call(ctx, Key("machines", region.Name)); err != nil {
+		return nil, err
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	var machines []*detect.Machine
+
+	for _, machine := range f.machines {
+
+		if machine.Region.Name == region.Name {
+			machines = append(machines, machine)
+		}
+
+	}
+
+	return machines, nil
+
+}
+
+func (f *Fake) Name() string {
+	return NAME
+}
+
+func (f *Fake) Prices(ctx context.Context, region *detect.Region, instance *detect.Instance, window time.Duration) (*detect.Prices, error) {
+
+	key := Key("prices", region.Name, instance.Name)
+
+	if err := f.call(ctx, key); err != nil {
+		return nil, err
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	prices, ok := f.prices[key]
+
+	if !ok {
+		return nil, fmt.Errorf("unknown instance %q in region %q", instance.Name, region.Name)
+	}
+
+	if prices == nil {
+		return nil, nil
+	}
+
+	clone := *prices
+	clone.Instance = instance
+
+	return &clone, nil
+
+}
+
+func (f *Fake) Regions(ctx context.Context) ([]*detect.Region, error) {
+
+	if err := f.call(ctx, Key("regions")); err != nil {
+		return nil, err
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	var regions []*detect.Region
+
+	for _, region := range f.regions {
+		clone := *region
+		regions = append(regions, &clone)
+	}
+
+	return regions, nil
+
+}
+
+func (f *Fake) Setup(ctx context.Context) error {
+
+	if err := f.call(ctx, Key("setup")); err != nil {
+		return err
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.setup = true
+
+	return nil
+
+}
+
+func (f *Fake) Teardown(ctx context.Context) error {
+
+	if err := f.call(ctx, Key("teardown")); err != nil {
+		return err
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if len(f.machines) > 0 {
+		return fmt.Errorf("instance profile still in use by %d machines - please terminate first", len(f.machines))
+	}
+
+	f.setup = false
+
+	return nil
+
+}
+
+func (f *Fake) Terminate(ctx context.Context, machine *detect.Machine) error {
+
+	if err := f.call(ctx, Key("terminate", machine.ID)); err != nil {
+		return err
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	idx := slices.IndexFunc(f.machines, func(m *detect.Machine) bool {
+		return m.ID == machine.ID
+	})
+
+	if idx == -1 {
+		return fmt.Errorf("unknown machine %q", machine.ID)
+	}
+
+	f.machines = slices.Delete(f.machines, idx, idx+1)
+
+	return nil
+
+}
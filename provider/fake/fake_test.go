@@ -0,0 +1,81 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yawn/instagpu/provider/contract"
+)
+
+func TestContract(t *testing.T) {
+
+	t.Run("discovery", func(t *testing.T) {
+		contract.Discovery(t, Sample())
+	})
+
+	t.Run("lifecycle", func(t *testing.T) {
+		contract.Lifecycle(t, Sample())
+	})
+
+}
+
+func TestFake(t *testing.T) {
+
+	assert := assert.New(t)
+
+	var (
+		ctx = context.Background()
+		f   = Sample()
+	)
+
+	f.Fail(Key("instances", "us-east-1"), fmt.Errorf("access denied"))
+
+	regions, err := f.Regions(ctx)
+
+	assert.NoError(err)
+	assert.Len(regions, 2)
+
+	_, _, err = f.Instances(ctx, regions[1])
+
+	assert.ErrorContains(err, "access denied")
+
+	instances, skipped, err := f.Instances(ctx, regions[0])
+
+	assert.NoError(err)
+	assert.Len(instances, 3)
+	assert.Empty(skipped)
+
+	f.Fail(Key("instances", "us-east-1"), nil)
+
+	_, skipped, err = f.Instances(ctx, regions[1])
+
+	if assert.NoError(err) && assert.Len(skipped, 1) {
+
+		assert.Equal("f1.2xlarge", skipped[0].Instance)
+
+		skipped[0] = nil
+
+		_, skipped, _ = f.Instances(ctx, regions[1])
+
+		assert.NotNil(skipped[0], "copied")
+
+	}
+
+	prices, err := f.Prices(ctx, regions[0], instances[2], contract.WINDOW)
+
+	assert.NoError(err)
+	assert.Nil(prices, "not available as spot")
+
+	f.Delay = 10 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(ctx, time.Millisecond)
+	defer cancel()
+
+	_, err = f.Regions(ctx)
+
+	assert.ErrorIs(err, context.DeadlineExceeded)
+
+}
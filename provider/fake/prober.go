@@ -0,0 +1,25 @@
+package fake
+
+import (
+	"context"
+	"time"
+)
+
+// Prober is a latency prober answering every sample after a fixed time, never touching the network
+type Prober time.Duration
+
+func (p Prober) Name() string {
+	return NAME
+}
+
+func (p Prober) Probe(ctx context.Context, endpoint string, samples int) ([]time.Duration, error) {
+
+	rtts := make([]time.Duration, samples)
+
+	for idx := range rtts {
+		rtts[idx] = time.Duration(p)
+	}
+
+	return rtts, nil
+
+}
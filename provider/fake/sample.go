@@ -0,0 +1,55 @@
+package fake
+
+import (
+	"github.com/yawn/instagpu/detect"
+)
+
+// Sample returns a fake with two regions and a handful of instances, one of them not available as spot and
+// one skipped
+func Sample() *Fake {
+
+	f := New()
+
+	f.AddRegion("eu-west-1")
+	f.AddRegion("us-east-1")
+
+	instance := func(name, vendor, gpu string, count uint, memory uint64) *detect.Instance {
+		return &detect.Instance{
+			Arch:   "x86_64",
+			Count:  4,
+			Memory: 16 * 1024,
+			Name:   name,
+			Vendor: "AMD",
+			GPU: &detect.GPU{
+				Count:  count,
+				Kind:   detect.KindGPU,
+				Memory: memory * 1024,
+				Name:   gpu,
+				Vendor: vendor,
+			},
+		}
+	}
+
+	prices := func(avg float64, azs uint) *detect.Prices {
+		return &detect.Prices{
+			AvailabilityZones: azs,
+			Avg:               avg,
+			Current:           avg,
+			Max:               avg * 1.5,
+			Median:            avg,
+			Min:               avg * 0.5,
+			P90:               avg * 1.25,
+			StdDev:            avg * 0.25,
+		}
+	}
+
+	f.AddInstance("eu-west-1", instance("g4ad.xlarge", "AMD", "Radeon Pro V520", 1, 8), prices(0.15, 2))
+	f.AddInstance("eu-west-1", instance("g5.xlarge", "NVIDIA", "A10G", 1, 24), prices(0.45, 3))
+	f.AddInstance("eu-west-1", instance("p4d.24xlarge", "NVIDIA", "A100", 8, 320), nil)
+	f.AddInstance("us-east-1", instance("g4dn.xlarge", "NVIDIA", "T4", 1, 16), prices(0.2, 6))
+	f.AddInstance("us-east-1", instance("g5.xlarge", "NVIDIA", "A10G", 1, 24), prices(0.4, 6))
+	f.AddSkipped("us-east-1", "f1.2xlarge", "no accelerator information") // an fpga
+
+	return f
+
+}
@@ -2,14 +2,33 @@ package provider
 
 import (
 	"context"
+	"time"
 
 	"github.com/yawn/instagpu/detect"
 )
 
+// LaunchOptions are provider independent settings for launching an instance
+type LaunchOptions struct {
+	Image    string  // machine image to boot, provider default if empty
+	Key      string  // name of a ssh key pair, optional
+	MaxPrice float64 // maximum spot price in USD / h, derived from prices if zero
+	Zone     string  // name of the availability zone to launch in, provider choice if empty
+}
+
+// Skipped is an instance type a provider cannot model and leaves out of its results
+type Skipped struct {
+	Instance string
+	Reason   string
+}
+
 type Provider interface {
-	Instances(ctx context.Context, region *detect.Region) ([]*detect.Instance, error)
+	Instances(ctx context.Context, region *detect.Region) ([]*detect.Instance, []*Skipped, error)
+	Launch(ctx context.Context, prices *detect.Prices, options *LaunchOptions) (*detect.Machine, error)
+	Machines(ctx context.Context, region *detect.Region) ([]*detect.Machine, error)
 	Name() string
-	Prices(ctx context.Context, region *detect.Region, instance *detect.Instance) (*detect.Prices, error)
+	Prices(ctx context.Context, region *detect.Region, instance *detect.Instance, window time.Duration) (*detect.Prices, error)
 	Regions(ctx context.Context) ([]*detect.Region, error)
 	Setup(ctx context.Context) error
+	Teardown(ctx context.Context) error
+	Terminate(ctx context.Context, machine *detect.Machine) error
 }